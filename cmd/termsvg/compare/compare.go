@@ -0,0 +1,106 @@
+package compare
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mrmarble/termsvg/cmd/termsvg/export"
+	"github.com/mrmarble/termsvg/internal/svg"
+	"github.com/mrmarble/termsvg/pkg/asciicast"
+	"github.com/rs/zerolog/log"
+)
+
+var errMismatch = errors.New("render differs from baseline")
+
+// Cmd takes the options of export, so the render matches what was exported with them.
+type Cmd struct {
+	File     string `arg:"" type:"existingfile" help:"asciicast file to render"`
+	Baseline string `required:"" type:"existingfile" help:"svg previously exported from the same file"`
+
+	export.Options `embed:""`
+}
+
+func (cmd *Cmd) Run() error {
+	opts, err := cmd.SvgOptions()
+	if err != nil {
+		return err
+	}
+
+	err = compare(cmd.File, cmd.Baseline, opts)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("baseline", cmd.Baseline).Msg("render matches baseline.")
+
+	return nil
+}
+
+func compare(input, baseline string, opts svg.Options) error {
+	inputFile, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+
+	cast, err := asciicast.Unmarshal(inputFile)
+	if err != nil {
+		return err
+	}
+
+	want, err := os.ReadFile(baseline)
+	if err != nil {
+		return err
+	}
+
+	got := new(bytes.Buffer)
+
+	_, err = svg.Export(*cast, got, opts)
+	if err != nil {
+		return err
+	}
+
+	return diff(want, got.Bytes())
+}
+
+// diff returns an error describing the first tag or text node that differs.
+// Whitespace between nodes is not significant.
+func diff(want, got []byte) error {
+	wantNodes := nodes(want)
+	gotNodes := nodes(got)
+
+	for i := 0; i < len(wantNodes) || i < len(gotNodes); i++ {
+		w, g := nodeAt(wantNodes, i), nodeAt(gotNodes, i)
+		if w != g {
+			return fmt.Errorf("%w at node %d:\n  baseline: %s\n  render:   %s", errMismatch, i+1, w, g)
+		}
+	}
+
+	return nil
+}
+
+// nodes splits an svg in its tags and text, dropping surrounding whitespace.
+func nodes(data []byte) []string {
+	split := strings.NewReplacer("<", "\n<", ">", ">\n").Replace(string(data))
+
+	var result []string
+
+	for _, node := range strings.Split(split, "\n") {
+		node = strings.Join(strings.Fields(node), " ")
+		if node != "" {
+			result = append(result, node)
+		}
+	}
+
+	return result
+}
+
+func nodeAt(nodes []string, i int) string {
+	if i < len(nodes) {
+		return nodes[i]
+	}
+
+	return "<EOF>"
+}
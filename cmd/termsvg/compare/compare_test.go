@@ -0,0 +1,120 @@
+package compare
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/mrmarble/termsvg/internal/svg"
+	"github.com/mrmarble/termsvg/pkg/asciicast"
+)
+
+func TestCompare(t *testing.T) {
+	baseline := writeBaseline(t, "hello", svg.Options{})
+
+	tests := map[string]struct {
+		data string
+		err  error
+	}{
+		"Identical": {"hello", nil},
+		"Changed":   {"hellO", errMismatch},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := compare(writeCast(t, tc.data), baseline, svg.Options{})
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestExportOptions(t *testing.T) {
+	input := writeCast(t, "hello")
+	baseline := writeBaseline(t, "hello", svg.Options{Theme: svg.LightTheme, Window: svg.WindowWindows})
+
+	tests := map[string]struct {
+		args []string
+		err  error
+	}{
+		"Same options":  {[]string{"--light", "--style", "windows"}, nil},
+		"Other options": {[]string{"--light"}, errMismatch},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var cmd Cmd
+
+			parser, err := kong.New(&cmd)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := parser.Parse(append([]string{input, "--baseline", baseline}, tc.args...)); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := cmd.Run(); !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestDiffIgnoresWhitespace(t *testing.T) {
+	want := []byte("<svg>\n<g>\n<text x=\"0\"  y=\"0\">a</text>\n</g>\n</svg>\n")
+	got := []byte("<svg><g>  <text x=\"0\" y=\"0\">a</text></g></svg>")
+
+	if err := diff(want, got); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newCast(data string) *asciicast.Cast {
+	cast := asciicast.New()
+	cast.Header.Width = 10
+	cast.Header.Height = 2
+	cast.Events = append(cast.Events, asciicast.Event{Time: 1, EventType: asciicast.Output, EventData: data})
+
+	return cast
+}
+
+func writeCast(t *testing.T, data string) string {
+	t.Helper()
+
+	js, err := newCast(data).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "input.cast")
+	if err := os.WriteFile(path, js, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func writeBaseline(t *testing.T, data string, opts svg.Options) string {
+	t.Helper()
+
+	cast := newCast(data)
+	cast.Header.Duration = 1
+
+	var output bytes.Buffer
+
+	if _, err := svg.Export(*cast, &output, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "baseline.svg")
+	if err := os.WriteFile(path, output.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
@@ -0,0 +1,5 @@
+package main
+
+// configFile holds the option defaults of the project in the working directory.
+// Keys are flag names with underscores, e.g. {"style": "windows", "min_contrast": 4.5}. Flags given override them.
+const configFile = ".termsvg.json"
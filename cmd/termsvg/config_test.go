@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/mrmarble/termsvg/cmd/termsvg/export"
+	"github.com/mrmarble/termsvg/internal/testutils"
+)
+
+func TestConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "rec.cast")
+	config := filepath.Join(dir, "termsvg.json")
+
+	if err := os.WriteFile(input, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	defaults := `{"style": "windows", "min_contrast": 4.5, "label": "me@host"}`
+	if err := os.WriteFile(config, []byte(defaults), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		args        []string
+		defaults    string
+		style       string
+		minContrast float64
+	}{
+		"Default file": {[]string{"export", input}, config, "windows", 4.5},
+		"Flag":         {[]string{"--config", config, "export", input}, "missing.json", "windows", 4.5},
+		"Override":     {[]string{"export", input, "--style", "plain"}, config, "plain", 4.5},
+		"No file":      {[]string{"export", input}, "missing.json", "macos", 0},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var cli struct {
+				Config kong.ConfigFlag
+				Export export.Cmd `cmd:""`
+			}
+
+			parser, err := kong.New(&cli, kong.Configuration(kong.JSON, tc.defaults))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = parser.Parse(tc.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			testutils.Diff(t, cli.Export.Style, tc.style)
+			testutils.Diff(t, cli.Export.MinContrast, tc.minContrast)
+		})
+	}
+}
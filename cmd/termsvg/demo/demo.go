@@ -0,0 +1,67 @@
+package demo
+
+import (
+	"embed"
+	"os"
+
+	"github.com/mrmarble/termsvg/internal/svg"
+	"github.com/mrmarble/termsvg/pkg/asciicast"
+	"github.com/rs/zerolog/log"
+)
+
+// Sample recordings bundled in the binary, copied from the examples folder.
+//
+//go:embed casts/*.cast
+var casts embed.FS
+
+type Cmd struct {
+	Sample string `optional:"" enum:"session,htop" default:"session" help:"bundled recording to render: session or htop"`
+	Output string `optional:"" short:"o" type:"path" default:"demo.svg" help:"where to save the file"`
+}
+
+func (cmd *Cmd) Run() error {
+	err := demo(cmd.Sample, cmd.Output)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("output", cmd.Output).Msg("svg file saved.")
+
+	return nil
+}
+
+func demo(sample, output string) error {
+	data, err := casts.ReadFile("casts/" + sample + ".cast")
+	if err != nil {
+		return err
+	}
+
+	cast, err := asciicast.Unmarshal(data)
+	if err != nil {
+		return err
+	}
+
+	return render(*cast, output)
+}
+
+// render exports cast to output, which is removed again if the export fails.
+func render(cast asciicast.Cast, output string) error {
+	outputFile, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer outputFile.Close()
+
+	_, err = svg.Export(cast, outputFile, svg.Options{})
+	if err != nil {
+		outputFile.Close()
+
+		if removeErr := os.Remove(output); removeErr != nil {
+			return removeErr
+		}
+
+		return err
+	}
+
+	return outputFile.Close()
+}
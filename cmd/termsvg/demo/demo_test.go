@@ -0,0 +1,55 @@
+package demo
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mrmarble/termsvg/internal/svg"
+	"github.com/mrmarble/termsvg/pkg/asciicast"
+)
+
+func TestDemo(t *testing.T) {
+	for _, sample := range []string{"session", "htop"} {
+		t.Run(sample, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "demo.svg")
+
+			err := demo(sample, output)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.HasPrefix(got, []byte("<?xml")) || !bytes.HasSuffix(bytes.TrimSpace(got), []byte("</svg>")) {
+				t.Fatalf("%s demo is not a complete svg", sample)
+			}
+		})
+	}
+}
+
+func TestRenderFailure(t *testing.T) {
+	cast := asciicast.New()
+	cast.Events = append(cast.Events, asciicast.Event{Time: 1, EventType: asciicast.Output, EventData: "hello"})
+
+	dir := t.TempDir()
+
+	err := render(*cast, filepath.Join(dir, "demo.svg"))
+	if !errors.Is(err, svg.ErrSize) {
+		t.Fatalf("expected ErrSize, got %v", err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 0 {
+		t.Fatalf("partial output left behind: %v", files)
+	}
+}
@@ -2,7 +2,15 @@ package export
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/mrmarble/termsvg/internal/svg"
 	"github.com/mrmarble/termsvg/pkg/asciicast"
@@ -11,13 +19,23 @@ import (
 	msvg "github.com/tdewolff/minify/v2/svg"
 )
 
+//nolint:lll // The flags are documented by their tags
 type Cmd struct {
-	File            string `arg:"" type:"existingfile" help:"asciicast file to export"`
-	Output          string `optional:"" short:"o" type:"path" help:"where to save the file. Defaults to <input_file>.svg"`
-	Mini            bool   `name:"minify" optional:"" short:"m" help:"minify output file. May be slower"`
-	NoWindow        bool   `name:"nowindow" optional:"" short:"n" help:"don't render terminal window in svg"`
-	BackgroundColor string `optional:"" short:"b" help:"background color in hexadecimal format (e.g. #FFFFFF)"`
-	TextColor       string `optional:"" short:"t" help:"text color in hexadecimal format (e.g. #000000)"`
+	File        string        `arg:"" type:"existingfile" help:"asciicast file to export"`
+	Output      string        `optional:"" short:"o" type:"path" help:"where to save the file. Defaults to <input_file>.svg"`
+	Mini        bool          `name:"minify" optional:"" short:"m" help:"minify output file. May be slower"`
+	TimeBudget  time.Duration `optional:"" help:"stop adding frames after this long and save what was drawn, e.g. 30s"`
+	MaxSize     int64         `optional:"" help:"abort if the rendered svg grows past this many bytes (0 for unlimited)"`
+	DataURI     bool          `name:"data-uri" optional:"" xor:"beside" help:"print the svg as a data uri instead of saving it, to paste in html or markdown"`
+	Beside      string        `optional:"" type:"existingfile" xor:"beside,embed" help:"another asciicast drawn to the right on the same timeline, for before and after demos"`
+	Stats       bool          `optional:"" help:"print the number of frames, output size and time taken"`
+	StatsFormat string        `optional:"" enum:"text,json" default:"text" help:"how --stats prints: a log line (text) or json on stdout"`
+	Poster      string        `optional:"" type:"path" help:"also save a still svg of the last frame with content, as a preview"`
+	PosterAt    time.Duration `optional:"" help:"time of the frame used by --poster instead, e.g. 2.5s"`
+	Storyboard  string        `optional:"" type:"path" placeholder:"DIR" help:"also save each distinct screen as its own svg in DIR, captioned with how long it shows"`
+	Palette     string        `name:"export-palette" optional:"" type:"path" help:"also save the colors used and their css classes as json"`
+
+	Options `embed:""`
 }
 
 func (cmd *Cmd) Run() error {
@@ -26,23 +44,356 @@ func (cmd *Cmd) Run() error {
 		output = cmd.File + ".svg"
 	}
 
-	err := export(cmd.File, output, cmd.Mini, cmd.BackgroundColor, cmd.TextColor, cmd.NoWindow)
+	opts, err := cmd.SvgOptions()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	if cmd.TimeBudget > 0 {
+		opts.Deadline = start.Add(cmd.TimeBudget)
+	}
+
+	if cmd.DataURI {
+		_, err = exportDataURI(cmd.File, os.Stdout, cmd.Mini, cmd.MaxSize, opts)
+
+		return err
+	}
+
+	var stats svg.Stats
+	if cmd.Beside != "" {
+		stats, err = exportSideBySide(cmd.File, cmd.Beside, output, cmd.Mini, cmd.MaxSize, opts)
+	} else {
+		stats, err = export(cmd.File, output, cmd.Mini, cmd.MaxSize, opts)
+	}
 	if err != nil {
 		return err
 	}
 
 	log.Info().Str("output", output).Msg("svg file saved.")
 
+	if cmd.Stats {
+		err = printStats(output, cmd.StatsFormat, stats, time.Since(start))
+		if err != nil {
+			return err
+		}
+	}
+
+	return cmd.exportExtras(opts)
+}
+
+// window returns the window of style, plain being NoWindow instead.
+func window(style string) svg.Window {
+	switch style {
+	case "minimal":
+		return svg.WindowMinimal
+	case "windows":
+		return svg.WindowWindows
+	default:
+		return svg.WindowMacOS
+	}
+}
+
+// layout returns the layout of the svg mode.
+func layout(mode string) svg.Layout {
+	if mode == "opacity" {
+		return svg.LayoutOpacity
+	}
+
+	return svg.LayoutTranslate
+}
+
+// parseAspect reads a WxH cell size, zero when empty so the default is used.
+func parseAspect(aspect string) (colWidth, rowHeight int, err error) {
+	if aspect == "" {
+		return 0, 0, nil
+	}
+
+	_, err = fmt.Sscanf(aspect, "%dx%d", &colWidth, &rowHeight)
+	if err != nil || colWidth <= 0 || rowHeight <= 0 {
+		return 0, 0, fmt.Errorf("invalid --aspect %q, expected WxH (e.g. 12x25)", aspect)
+	}
+
+	return colWidth, rowHeight, nil
+}
+
+// parsePadding reads a T,R,B,L padding, nil when empty so the window style picks it.
+func parsePadding(text string) (*svg.Padding, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	padding := &svg.Padding{}
+
+	_, err := fmt.Sscanf(text, "%d,%d,%d,%d", &padding.Top, &padding.Right, &padding.Bottom, &padding.Left)
+	if err != nil || padding.Top < 0 || padding.Right < 0 || padding.Bottom < 0 || padding.Left < 0 {
+		return nil, fmt.Errorf("invalid --padding %q, expected T,R,B,L (e.g. 20,20,40,20)", text)
+	}
+
+	return padding, nil
+}
+
+// printStats reports how the export of output went, as a log line or json on stdout.
+func printStats(output, format string, stats svg.Stats, took time.Duration) error {
+	info, err := os.Stat(output)
+	if err != nil {
+		return err
+	}
+
+	if format != "json" {
+		log.Info().Msg(summary(stats, info.Size(), took))
+
+		return nil
+	}
+
+	js, err := summaryJSON(stats, info.Size(), took)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(js))
+
+	return nil
+}
+
+// exportExtras saves the files asked for along with the svg: poster, storyboard and palette.
+func (cmd *Cmd) exportExtras(opts svg.Options) error {
+	if cmd.Poster != "" {
+		at := cmd.PosterAt.Seconds()
+		if at == 0 {
+			at = -1
+		}
+
+		if err := exportPoster(cmd.File, cmd.Poster, opts, at); err != nil {
+			return err
+		}
+
+		log.Info().Str("output", cmd.Poster).Msg("poster saved.")
+	}
+
+	if cmd.Storyboard != "" {
+		boards, err := exportStoryboard(cmd.File, cmd.Storyboard, opts)
+		if err != nil {
+			return err
+		}
+
+		log.Info().Str("output", cmd.Storyboard).Int("boards", boards).Msg("storyboard saved.")
+	}
+
+	if cmd.Palette != "" {
+		if err := exportPalette(cmd.File, cmd.Palette, opts); err != nil {
+			return err
+		}
+
+		log.Info().Str("output", cmd.Palette).Msg("palette saved.")
+	}
+
 	return nil
 }
 
-func export(input, output string, mini bool, bgColor, textColor string, noWindow bool) error {
+func export(input, output string, mini bool, maxSize int64, opts svg.Options) (svg.Stats, error) {
+	cast, err := readCast(input)
+	if err != nil {
+		return svg.Stats{}, err
+	}
+
+	return save(output, mini, maxSize, func(w svg.Output) (svg.Stats, error) {
+		return svg.Export(*cast, w, opts)
+	})
+}
+
+// exportSideBySide saves left and right drawn next to each other, see svg.SideBySide.
+func exportSideBySide(left, right, output string, mini bool, maxSize int64, opts svg.Options) (svg.Stats, error) {
+	leftCast, err := readCast(left)
+	if err != nil {
+		return svg.Stats{}, err
+	}
+
+	rightCast, err := readCast(right)
+	if err != nil {
+		return svg.Stats{}, err
+	}
+
+	return save(output, mini, maxSize, func(w svg.Output) (svg.Stats, error) {
+		return svg.SideBySide(*leftCast, *rightCast, w, opts)
+	})
+}
+
+// exportDataURI prints the svg of input as a data uri on w, to paste in html or markdown.
+func exportDataURI(input string, w io.Writer, mini bool, maxSize int64, opts svg.Options) (svg.Stats, error) {
+	cast, err := readCast(input)
+	if err != nil {
+		return svg.Stats{}, err
+	}
+
+	out := new(bytes.Buffer)
+
+	stats, err := render(out, mini, maxSize, func(dst svg.Output) (svg.Stats, error) {
+		return svg.Export(*cast, dst, opts)
+	})
+	if errors.Is(err, errMaxSize) {
+		return stats, tooLarge(maxSize)
+	}
+	if err != nil {
+		return stats, err
+	}
+
+	_, err = fmt.Fprintln(w, svg.DataURI(out.Bytes()))
+
+	return stats, err
+}
+
+// save writes what draw renders to output, minified if asked, unless it grows past maxSize.
+// Nothing is left behind when draw fails.
+func save(output string, mini bool, maxSize int64, draw func(svg.Output) (svg.Stats, error)) (svg.Stats, error) {
+	outputFile, err := os.Create(output)
+	if err != nil {
+		return svg.Stats{}, err
+	}
+	defer outputFile.Close()
+
+	stats, err := render(outputFile, mini, maxSize, draw)
+	if errors.Is(err, errMaxSize) {
+		return stats, discard(outputFile, maxSize)
+	}
+	if err != nil {
+		return stats, remove(outputFile, err)
+	}
+
+	return stats, nil
+}
+
+// render writes what draw renders to w, minified if asked. It fails with errMaxSize past maxSize bytes.
+func render(w io.Writer, mini bool, maxSize int64, draw func(svg.Output) (svg.Stats, error)) (svg.Stats, error) {
+	if !mini {
+		limited := &limitedWriter{Writer: w, limit: maxSize}
+		stats, err := draw(limited)
+
+		if limited.exceeded {
+			return stats, errMaxSize
+		}
+
+		return stats, err
+	}
+
+	// The limit applies to what gets written, so it waits for the minified bytes
+	out := new(bytes.Buffer)
+
+	stats, err := draw(out)
+	if err != nil {
+		return stats, err
+	}
+
+	m := minify.New()
+	m.AddFunc("image/svg+xml", msvg.Minify)
+
+	b, err := m.Bytes("image/svg+xml", out.Bytes())
+	if err != nil {
+		return stats, err
+	}
+
+	limited := &limitedWriter{Writer: w, limit: maxSize}
+	_, err = limited.Write(b)
+
+	if limited.exceeded {
+		return stats, errMaxSize
+	}
+
+	return stats, err
+}
+
+var hexColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// parseColorMap reads FROM=TO pairs of hex colors, keyed in lowercase as svg.Options wants them.
+func parseColorMap(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	colorMap := make(map[string]string, len(pairs))
+
+	for _, pair := range pairs {
+		colors := strings.Split(pair, "=")
+		if len(colors) != 2 || !hexColor.MatchString(colors[0]) || !hexColor.MatchString(colors[1]) {
+			return nil, fmt.Errorf("invalid --map-color %q, expected FROM=TO (e.g. #cd0000=#00ff00)", pair)
+		}
+
+		colorMap[strings.ToLower(colors[0])] = strings.ToLower(colors[1])
+	}
+
+	return colorMap, nil
+}
+
+// readCallouts loads the notes of the --annotations file, none without one.
+func readCallouts(path string) ([]svg.Callout, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var callouts []svg.Callout
+	if err := json.Unmarshal(data, &callouts); err != nil {
+		return nil, fmt.Errorf("invalid --annotations %q: %w", path, err)
+	}
+
+	return callouts, nil
+}
+
+// readCast loads the recording to export.
+func readCast(input string) (*asciicast.Cast, error) {
 	inputFile, err := os.ReadFile(input)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	cast, err := asciicast.Unmarshal(inputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cast.HasOutput() {
+		return nil, asciicast.ErrNoOutput
+	}
+
+	if cast.DefaultSize {
+		log.Warn().Int("width", cast.Header.Width).Int("height", cast.Header.Height).
+			Msg("recording without a terminal size, using the default.")
+	}
+
+	return cast, nil
+}
+
+// summary describes an export in a line.
+func summary(stats svg.Stats, size int64, elapsed time.Duration) string {
+	return fmt.Sprintf("%d frames (%d duplicates), %d bytes in %s",
+		stats.Frames, stats.Duplicates, size, elapsed.Round(time.Millisecond))
+}
+
+// report is the json printed by --stats-format json. Fields are only ever added, scripts can rely on them.
+type report struct {
+	Frames     int     `json:"frames"`
+	Duplicates int     `json:"duplicates"`
+	Bytes      int64   `json:"bytes"`
+	Seconds    float64 `json:"seconds"`
+}
+
+// summaryJSON describes an export for scripts.
+func summaryJSON(stats svg.Stats, size int64, elapsed time.Duration) ([]byte, error) {
+	return json.Marshal(report{
+		Frames:     stats.Frames,
+		Duplicates: stats.Duplicates,
+		Bytes:      size,
+		Seconds:    elapsed.Round(time.Millisecond).Seconds(),
+	})
+}
+
+// exportPoster saves the still of the frame at the given second, see svg.Poster.
+func exportPoster(input, output string, opts svg.Options, at float64) error {
+	cast, err := readCast(input)
 	if err != nil {
 		return err
 	}
@@ -53,25 +404,56 @@ func export(input, output string, mini bool, bgColor, textColor string, noWindow
 	}
 	defer outputFile.Close()
 
-	if mini {
-		out := new(bytes.Buffer)
-		svg.Export(*cast, out, bgColor, textColor, noWindow)
+	err = svg.Poster(*cast, outputFile, opts, at)
+	if err != nil {
+		return remove(outputFile, err)
+	}
 
-		m := minify.New()
-		m.AddFunc("image/svg+xml", msvg.Minify)
+	return nil
+}
 
-		b, err := m.Bytes("image/svg+xml", out.Bytes())
-		if err != nil {
-			return err
-		}
+// exportStoryboard saves the boards as frame-001.svg, frame-002.svg... in dir and returns how many.
+func exportStoryboard(input, dir string, opts svg.Options) (int, error) {
+	cast, err := readCast(input)
+	if err != nil {
+		return 0, err
+	}
+
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return 0, err
+	}
+
+	boards, err := svg.Storyboard(*cast, opts)
+	if err != nil {
+		return 0, err
+	}
 
-		_, err = outputFile.Write(b)
+	for i, board := range boards {
+		err = os.WriteFile(filepath.Join(dir, fmt.Sprintf("frame-%03d.svg", i+1)), board, os.ModePerm)
 		if err != nil {
-			return err
+			return 0, err
 		}
-	} else {
-		svg.Export(*cast, outputFile, bgColor, textColor, noWindow)
 	}
 
-	return nil
+	return len(boards), nil
+}
+
+func exportPalette(input, output string, opts svg.Options) error {
+	cast, err := readCast(input)
+	if err != nil {
+		return err
+	}
+
+	palette, err := svg.Palette(*cast, opts)
+	if err != nil {
+		return err
+	}
+
+	js, err := json.MarshalIndent(palette, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(output, js, os.ModePerm)
 }
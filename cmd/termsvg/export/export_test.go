@@ -0,0 +1,357 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/mrmarble/termsvg/internal/svg"
+	"github.com/mrmarble/termsvg/internal/testutils"
+	"github.com/mrmarble/termsvg/pkg/asciicast"
+)
+
+func TestMaxSize(t *testing.T) {
+	input := writeCast(t)
+
+	tests := map[string]struct {
+		mini bool
+	}{
+		"Plain":    {false},
+		"Minified": {true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "out.svg")
+
+			_, err := export(input, output, tc.mini, 100, svg.Options{})
+			if !errors.Is(err, errMaxSize) {
+				t.Fatalf("expected max size error, got %v", err)
+			}
+
+			if _, err := os.Stat(output); !os.IsNotExist(err) {
+				t.Fatalf("partial output was not removed: %v", err)
+			}
+		})
+	}
+}
+
+func TestMaxSizeUnderLimit(t *testing.T) {
+	input := writeCast(t)
+	output := filepath.Join(t.TempDir(), "out.svg")
+
+	_, err := export(input, output, false, 1<<20, svg.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(output); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMaxSizeMinified(t *testing.T) {
+	input := writeCast(t)
+	dir := t.TempDir()
+
+	sizes := map[bool]int64{}
+
+	for _, mini := range []bool{false, true} {
+		output := filepath.Join(dir, fmt.Sprintf("%t.svg", mini))
+
+		if _, err := export(input, output, mini, 0, svg.Options{}); err != nil {
+			t.Fatal(err)
+		}
+
+		info, err := os.Stat(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sizes[mini] = info.Size()
+	}
+
+	if sizes[true] >= sizes[false] {
+		t.Fatalf("minified to %d bytes, not smaller than %d", sizes[true], sizes[false])
+	}
+
+	// The limit only fits the minified output
+	output := filepath.Join(dir, "out.svg")
+
+	if _, err := export(input, output, true, (sizes[false]+sizes[true])/2, svg.Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(output); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNoOutput(t *testing.T) {
+	cast := asciicast.New()
+	cast.Header.Width = 20
+	cast.Header.Height = 2
+	cast.Events = append(cast.Events, asciicast.Event{Time: 1, EventType: asciicast.Input, EventData: "ls\r"})
+
+	data, err := cast.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.cast")
+	if err := os.WriteFile(input, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, "out.svg")
+
+	_, err = export(input, output, false, 0, svg.Options{})
+	if !errors.Is(err, asciicast.ErrNoOutput) {
+		t.Fatalf("expected no output error, got %v", err)
+	}
+
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Fatalf("output was created: %v", err)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	input := writeCast(t)
+	output := filepath.Join(t.TempDir(), "out.svg")
+
+	stats, err := export(input, output, false, 1<<20, svg.Options{DedupFrames: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := fmt.Sprintf("2 frames (0 duplicates), %d bytes in 15ms", info.Size())
+	testutils.Diff(t, summary(stats, info.Size(), 15*time.Millisecond), want)
+}
+
+func TestSummaryJSON(t *testing.T) {
+	js, err := summaryJSON(svg.Stats{Frames: 3, Duplicates: 1}, 2048, 1500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]interface{}
+
+	err = json.Unmarshal(js, &got)
+	if err != nil {
+		t.Fatalf("invalid json %s: %v", js, err)
+	}
+
+	want := map[string]interface{}{"frames": 3., "duplicates": 1., "bytes": 2048., "seconds": 1.5}
+	testutils.Diff(t, got, want)
+}
+
+func TestParseColorMap(t *testing.T) {
+	tests := map[string]struct {
+		pairs []string
+		want  map[string]string
+		err   bool
+	}{
+		"None": {nil, nil, false},
+		"Multiple": {
+			[]string{"#CD0000=#00ff00", "#00cd00=#123ABC"}, map[string]string{"#cd0000": "#00ff00", "#00cd00": "#123abc"}, false,
+		},
+		"No target": {[]string{"#cd0000"}, nil, true},
+		"Not hex":   {[]string{"red=#00ff00"}, nil, true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseColorMap(tc.pairs)
+			if (err != nil) != tc.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			testutils.Diff(t, got, tc.want)
+		})
+	}
+}
+
+func TestExportPoster(t *testing.T) {
+	input := writeCast(t)
+	dir := t.TempDir()
+
+	_, err := export(input, filepath.Join(dir, "out.svg"), false, 0, svg.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = exportPoster(input, filepath.Join(dir, "poster.svg"), svg.Options{}, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The animation and its still, showing the last frame
+	for name, text := range map[string]string{"out.svg": "hello", "poster.svg": "world"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.HasPrefix(data, []byte("<?xml")) || !bytes.Contains(data, []byte(text)) {
+			t.Fatalf("%s not found in %s:\n%s", text, name, data)
+		}
+	}
+}
+
+func TestExportDataURI(t *testing.T) {
+	input := writeCast(t)
+
+	for name, mini := range map[string]bool{"Plain": false, "Minified": true} {
+		t.Run(name, func(t *testing.T) {
+			var output bytes.Buffer
+
+			_, err := exportDataURI(input, &output, mini, 0, svg.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			const prefix = "data:image/svg+xml;base64,"
+
+			uri := strings.TrimSuffix(output.String(), "\n")
+			if !strings.HasPrefix(uri, prefix) {
+				t.Fatalf("not an svg data uri: %s", uri)
+			}
+
+			data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(uri, prefix))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Contains(data, []byte("<svg")) || !bytes.Contains(data, []byte("hello")) {
+				t.Fatalf("recording not found in data uri:\n%s", data)
+			}
+		})
+	}
+
+	_, err := exportDataURI(input, new(bytes.Buffer), false, 100, svg.Options{})
+	if !errors.Is(err, errMaxSize) {
+		t.Fatalf("expected errMaxSize, got %v", err)
+	}
+}
+
+func TestExportStoryboard(t *testing.T) {
+	input := writeCast(t)
+	dir := filepath.Join(t.TempDir(), "boards")
+
+	boards, err := exportStoryboard(input, dir, svg.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "frame-*.svg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testutils.Diff(t, boards, 2)
+	testutils.Diff(t, len(files), boards)
+	testutils.Diff(t, filepath.Base(files[0]), "frame-001.svg")
+}
+
+func TestExportPalette(t *testing.T) {
+	input := writeCast(t)
+	output := filepath.Join(t.TempDir(), "palette.json")
+
+	err := exportPalette(input, output, svg.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var palette []map[string]string
+	if err := json.Unmarshal(data, &palette); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []map[string]string{
+		{"hex": "#cd0000", "class": "a"},
+		{"hex": "#e5e5e5", "class": "b"},
+	}
+	if !reflect.DeepEqual(palette, want) {
+		t.Fatalf("expected %v, got %v", want, palette)
+	}
+}
+
+func writeCast(t *testing.T) string {
+	t.Helper()
+
+	cast := asciicast.New()
+	cast.Header.Width = 20
+	cast.Header.Height = 2
+	cast.Events = append(cast.Events,
+		asciicast.Event{Time: 1, EventType: asciicast.Output, EventData: "\u001b[31mhello\u001b[0m"},
+		asciicast.Event{Time: 2, EventType: asciicast.Output, EventData: " world"},
+	)
+
+	data, err := cast.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "input.cast")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestExclusiveFlags(t *testing.T) {
+	input := writeCast(t)
+
+	tests := map[string][]string{
+		"Embedded cast beside": {input, "--beside", input, "--embed-cast"},
+		"Data uri beside":      {input, "--beside", input, "--data-uri"},
+	}
+
+	for name, args := range tests {
+		t.Run(name, func(t *testing.T) {
+			var cmd Cmd
+
+			parser, err := kong.New(&cmd)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := parser.Parse(args); err == nil {
+				t.Fatal("expected the flags to be rejected together")
+			}
+		})
+	}
+
+	// Data uris can carry the recording
+	var cmd Cmd
+
+	parser, err := kong.New(&cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parser.Parse([]string{input, "--data-uri", "--embed-cast"}); err != nil {
+		t.Fatal(err)
+	}
+}
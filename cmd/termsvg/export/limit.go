@@ -0,0 +1,61 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var errMaxSize = errors.New("output exceeds the maximum size")
+
+// limitedWriter stops writing once more than limit bytes went through it.
+// A limit of 0 means no limit.
+type limitedWriter struct {
+	io.Writer
+	limit    int64
+	written  int64
+	exceeded bool
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.exceeded {
+		return 0, errMaxSize
+	}
+
+	l.written += int64(len(p))
+	if l.limit > 0 && l.written > l.limit {
+		l.exceeded = true
+
+		return 0, errMaxSize
+	}
+
+	return l.Writer.Write(p)
+}
+
+// discard removes the partially written output and explains how to shrink it.
+func discard(file *os.File, limit int64) error {
+	file.Close()
+
+	if err := os.Remove(file.Name()); err != nil {
+		return err
+	}
+
+	return tooLarge(limit)
+}
+
+// tooLarge explains how to get the output under limit bytes.
+func tooLarge(limit int64) error {
+	return fmt.Errorf("%w of %d bytes: export a shorter recording, use --minify or raise --max-size", errMaxSize, limit)
+}
+
+// remove deletes the partially written output and returns the error that interrupted it.
+func remove(file *os.File, cause error) error {
+	file.Close()
+
+	if err := os.Remove(file.Name()); err != nil {
+		return err
+	}
+
+	return cause
+}
@@ -0,0 +1,140 @@
+package export
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mrmarble/termsvg/internal/svg"
+)
+
+// Options are the flags changing how a recording is drawn, shared by the commands rendering one.
+//
+//nolint:lll // The flags are documented by their tags
+type Options struct {
+	NoWindow        bool          `name:"nowindow" optional:"" short:"n" help:"don't render terminal window in svg"`
+	BackgroundColor string        `optional:"" short:"b" help:"background color in hexadecimal format (e.g. #FFFFFF)"`
+	WindowColor     string        `optional:"" help:"window title bar color in hexadecimal format, the background color by default"`
+	TextColor       string        `optional:"" short:"t" help:"text color in hexadecimal format (e.g. #000000)"`
+	Dark            bool          `optional:"" xor:"theme" help:"use the built-in dark theme"`
+	Light           bool          `optional:"" xor:"theme" help:"use the built-in light theme"`
+	Label           string        `optional:"" help:"text to show in the window title bar (e.g. user@host:~)"`
+	AnchorBottom    bool          `optional:"" help:"keep the last line of each frame on the bottom row, like a log being tailed"`
+	TrimBlankRows   bool          `optional:"" help:"drop the bottom rows that stay empty during the whole recording"`
+	MaxFrames       int           `optional:"" help:"render at most this many frames, evenly picked (0 for unlimited)"`
+	Style           string        `optional:"" enum:"macos,windows,plain,minimal" default:"macos" help:"window style: macos, windows, plain or minimal"`
+	Normalize       bool          `name:"normalize-unicode" optional:"" help:"compose equivalent unicode sequences (NFC) before rendering"`
+	DedupFrames     bool          `optional:"" help:"draw repeated frames once and reference them. Makes smaller files"`
+	DropIdleFrames  bool          `optional:"" help:"merge frames that don't change the screen into the previous one"`
+	DeltaFrames     bool          `optional:"" help:"draw each distinct line once and reuse it in later frames, smaller for long recordings"`
+	EmbedCast       bool          `optional:"" xor:"embed" help:"store the recording in the svg, termsvg extract gets it back"`
+	SeamlessLoop    bool          `optional:"" help:"hold the first frame again at the end so it loops without a jump"`
+	StartPaused     bool          `optional:"" help:"show the first frame until the pointer hovers the svg"`
+	Font            string        `optional:"" help:"font asked for first, its cell size is used unless --aspect is given: cascadia-code, fira-code, jetbrains-mono, source-code-pro or ubuntu-mono"`
+	Aspect          string        `optional:"" placeholder:"WxH" help:"cell size in pixels for fonts with other proportions (default 12x25)"`
+	SvgMode         string        `name:"svg-mode" optional:"" enum:"translate,opacity" default:"translate" help:"how frames are animated: slid side by side (translate) or stacked and shown in turn (opacity)"`
+	WidthPx         int           `name:"width-px" optional:"" placeholder:"PX" help:"scale the svg to this width in pixels"`
+	ShowClock       bool          `optional:"" help:"show the time elapsed since the start in the top right corner"`
+	Linkify         bool          `optional:"" help:"make the urls printed in the recording clickable"`
+	Annotations     string        `optional:"" type:"existingfile" help:"json file of notes to show over the terminal: [{\"time\":1.5,\"duration\":2,\"text\":\"...\",\"row\":3,\"col\":10}]"`
+	Caption         string        `optional:"" help:"text shown below the terminal, \\n starts a new line"`
+	Padding         string        `optional:"" placeholder:"T,R,B,L" help:"room around the terminal in pixels: top, right, bottom and left"`
+	MergeGap        int           `optional:"" placeholder:"SPACES" help:"join text runs of the same style separated by at most this many spaces, fewer elements but a less exact layout"`
+	FlatColor       bool          `optional:"" help:"skip the css classes when the recording prints in a single color, smaller plain text svgs"`
+	GridAlign       bool          `optional:"" help:"stretch text runs to their cells, keeps columns aligned with fonts of other widths"`
+	TmuxPassthrough bool          `optional:"" help:"replay the sequences tmux wraps in device control strings instead of dropping them"`
+	Overstrike      bool          `optional:"" help:"render backspace overstrikes (as printed by man) as bold and underline"`
+	MapColor        []string      `optional:"" placeholder:"FROM=TO" help:"replace a color by another, e.g. #cd0000=#00ff00. Can be repeated"`
+	MinContrast     float64       `name:"min-contrast" optional:"" placeholder:"RATIO" help:"lighten text colors below this contrast ratio against the background (4.5 is the WCAG minimum)"`
+	Speed           float64       `optional:"" default:"1.0" help:"playback speed, below 1 for slow motion (e.g. 0.5)"`
+	MinDwell        time.Duration `optional:"" help:"show every frame at least this long, e.g. 50ms"`
+	MaxDwell        time.Duration `optional:"" help:"show every frame at most this long, e.g. 2s"`
+	FullScrollback  bool          `optional:"" help:"draw everything printed as one tall static image instead of an animation"`
+	ScrollbackLines int           `optional:"" default:"10000" help:"rows scrolled off the top kept by --full-scrollback, the oldest are dropped (0 for unlimited)"`
+	MinDuration     time.Duration `optional:"" help:"hold the last frame so the animation lasts at least this long, e.g. 1s"`
+}
+
+// SvgOptions returns the svg options the flags ask for.
+func (flags *Options) SvgOptions() (svg.Options, error) {
+	opts := svg.Options{
+		Theme:            flags.theme(),
+		NoWindow:         flags.NoWindow || flags.Style == "plain",
+		Window:           window(flags.Style),
+		Label:            flags.Label,
+		TrimBlankRows:    flags.TrimBlankRows,
+		AnchorBottom:     flags.AnchorBottom,
+		MaxFrames:        flags.MaxFrames,
+		NormalizeUnicode: flags.Normalize,
+		DedupFrames:      flags.DedupFrames,
+		StartPaused:      flags.StartPaused,
+		SeamlessLoop:     flags.SeamlessLoop,
+		EmbedCast:        flags.EmbedCast,
+		DeltaFrames:      flags.DeltaFrames,
+		DropIdleFrames:   flags.DropIdleFrames,
+		Font:             flags.Font,
+		Overstrike:       flags.Overstrike,
+		TmuxPassthrough:  flags.TmuxPassthrough,
+		GridAlign:        flags.GridAlign,
+		MergeGap:         flags.MergeGap,
+		FlatColor:        flags.FlatColor,
+		MinContrast:      flags.MinContrast,
+		Width:            flags.WidthPx,
+		Layout:           layout(flags.SvgMode),
+		Caption:          strings.ReplaceAll(flags.Caption, `\n`, "\n"),
+		ShowClock:        flags.ShowClock,
+		Linkify:          flags.Linkify,
+		Speed:            flags.Speed,
+		MinDwell:         flags.MinDwell.Seconds(),
+		MaxDwell:         flags.MaxDwell.Seconds(),
+		MinDuration:      flags.MinDuration.Seconds(),
+		FullScrollback:   flags.FullScrollback,
+		ScrollbackLines:  flags.ScrollbackLines,
+	}
+
+	return opts, flags.parseOptions(&opts)
+}
+
+// parseOptions fills the options given as text in the flags, failing on invalid ones.
+func (flags *Options) parseOptions(opts *svg.Options) error {
+	var err error
+
+	opts.ColorMap, err = parseColorMap(flags.MapColor)
+	if err != nil {
+		return err
+	}
+
+	opts.ColWidth, opts.RowHeight, err = parseAspect(flags.Aspect)
+	if err != nil {
+		return err
+	}
+
+	opts.Padding, err = parsePadding(flags.Padding)
+	if err != nil {
+		return err
+	}
+
+	opts.Callouts, err = readCallouts(flags.Annotations)
+
+	return err
+}
+
+// theme returns the theme picked, with the colors given on top.
+func (flags *Options) theme() svg.Theme {
+	theme := svg.DarkTheme
+	if flags.Light {
+		theme = svg.LightTheme
+	}
+
+	if flags.BackgroundColor != "" {
+		theme.Background = flags.BackgroundColor
+	}
+
+	if flags.WindowColor != "" {
+		theme.Window = flags.WindowColor
+	}
+
+	if flags.TextColor != "" {
+		theme.Foreground = flags.TextColor
+	}
+
+	return theme
+}
@@ -0,0 +1,49 @@
+package extract
+
+import (
+	"os"
+	"strings"
+
+	"github.com/mrmarble/termsvg/internal/svg"
+	"github.com/rs/zerolog/log"
+)
+
+type Cmd struct {
+	File   string `arg:"" type:"existingfile" help:"svg exported with --embed-cast"`
+	Output string `optional:"" short:"o" type:"path" help:"where to save the recording. Defaults to <input_file> with .cast extension"` //nolint:lll
+}
+
+func (cmd *Cmd) Run() error {
+	output := cmd.Output
+	if output == "" {
+		output = strings.TrimSuffix(cmd.File, ".svg") + ".cast"
+	}
+
+	err := extract(cmd.File, output)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("output", output).Msg("asciicast saved.")
+
+	return nil
+}
+
+func extract(input, output string) error {
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+
+	cast, err := svg.Extract(data)
+	if err != nil {
+		return err
+	}
+
+	js, err := cast.Marshal()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(output, js, os.ModePerm)
+}
@@ -0,0 +1,72 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mrmarble/termsvg/internal/svg"
+	"github.com/mrmarble/termsvg/pkg/asciicast"
+)
+
+func TestExtract(t *testing.T) {
+	cast := asciicast.New()
+	cast.Header.Width = 10
+	cast.Header.Height = 2
+	cast.Events = append(cast.Events,
+		asciicast.Event{Time: 0.5, EventType: asciicast.Output, EventData: "hello"},
+		asciicast.Event{Time: 1.25, EventType: asciicast.Output, EventData: " <world> & \"more\""},
+	)
+	cast.RecomputeDuration()
+
+	want, err := cast.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		embed bool
+		err   error
+	}{
+		"Embedded":     {true, nil},
+		"Not embedded": {false, svg.ErrNoCast},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var rendered bytes.Buffer
+
+			if _, err := svg.Export(*cast, &rendered, svg.Options{EmbedCast: tc.embed}); err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			input := filepath.Join(dir, "rec.svg")
+			output := filepath.Join(dir, "rec.cast")
+
+			if err := os.WriteFile(input, rendered.Bytes(), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			err := extract(input, output)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+
+			if tc.err != nil {
+				return
+			}
+
+			got, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Fatalf("recovered recording differs:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
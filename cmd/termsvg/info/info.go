@@ -0,0 +1,54 @@
+package info
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mrmarble/termsvg/pkg/asciicast"
+)
+
+type Cmd struct {
+	File string `arg:"" type:"existingfile" help:"asciicast file"`
+}
+
+func (cmd *Cmd) Run() error {
+	return info(cmd.File, os.Stdout)
+}
+
+// info prints what the header of the recording at path says, and how long it plays.
+func info(path string, out io.Writer) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	cast, err := asciicast.Unmarshal(data)
+	if err != nil {
+		return err
+	}
+
+	size := fmt.Sprintf("%dx%d", cast.Header.Width, cast.Header.Height)
+	if cast.DefaultSize {
+		size += " (default)"
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
+	fields := [][2]string{
+		{"Size", size},
+		{"Duration", fmt.Sprintf("%.2fs", cast.Header.Duration)},
+		{"Events", fmt.Sprint(len(cast.Events))},
+		{"Command", cast.Header.Command},
+		{"Shell", cast.Header.Env.Shell},
+		{"Term", cast.Header.Env.Term},
+	}
+
+	for _, field := range fields {
+		if field[1] != "" {
+			fmt.Fprintf(w, "%s:\t%s\n", field[0], field[1])
+		}
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,48 @@
+package info
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mrmarble/termsvg/internal/testutils"
+	"github.com/mrmarble/termsvg/pkg/asciicast"
+)
+
+func TestInfo(t *testing.T) {
+	t.Setenv("SHELL", "/bin/zsh")
+	t.Setenv("TERM", "xterm-256color")
+
+	cast := asciicast.New()
+	cast.Header.Width = 80
+	cast.Header.Height = 24
+	cast.Header.Command = "htop"
+	cast.Events = append(cast.Events,
+		asciicast.Event{Time: 0.5, EventType: asciicast.Output, EventData: "hello"},
+		asciicast.Event{Time: 1.25, EventType: asciicast.Output, EventData: " world"},
+	)
+
+	data, err := cast.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := filepath.Join(t.TempDir(), "rec.cast")
+	if err := os.WriteFile(input, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := info(input, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "Size:     80x24\n" +
+		"Duration: 1.25s\n" +
+		"Events:   2\n" +
+		"Command:  htop\n" +
+		"Shell:    /bin/zsh\n" +
+		"Term:     xterm-256color\n"
+	testutils.Diff(t, out.String(), want)
+}
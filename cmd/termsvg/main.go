@@ -7,7 +7,11 @@ import (
 	"os"
 
 	"github.com/alecthomas/kong"
+	"github.com/mrmarble/termsvg/cmd/termsvg/compare"
+	"github.com/mrmarble/termsvg/cmd/termsvg/demo"
 	"github.com/mrmarble/termsvg/cmd/termsvg/export"
+	"github.com/mrmarble/termsvg/cmd/termsvg/extract"
+	"github.com/mrmarble/termsvg/cmd/termsvg/info"
 	"github.com/mrmarble/termsvg/cmd/termsvg/play"
 	"github.com/mrmarble/termsvg/cmd/termsvg/rec"
 	"github.com/rs/zerolog"
@@ -44,18 +48,24 @@ func (v VersionFlag) BeforeApply(app *kong.Kong) error {
 
 func main() {
 	var cli struct {
-		Debug   bool        `help:"Enable debug mode."`
-		Version VersionFlag `name:"version" help:"Print version information and quit"`
+		Debug   bool            `help:"Enable debug mode."`
+		Version VersionFlag     `name:"version" help:"Print version information and quit"`
+		Config  kong.ConfigFlag `placeholder:"PATH" help:"Load option defaults from this json file, .termsvg.json is read if present"` //nolint:lll
 
-		Play   play.Cmd   `cmd:"" help:"Play a recording."`
-		Rec    rec.Cmd    `cmd:"" help:"Record a terminal sesion."`
-		Export export.Cmd `cmd:"" help:"Export asciicast."`
+		Play    play.Cmd    `cmd:"" help:"Play a recording."`
+		Rec     rec.Cmd     `cmd:"" help:"Record a terminal sesion."`
+		Export  export.Cmd  `cmd:"" help:"Export asciicast."`
+		Compare compare.Cmd `cmd:"" help:"Compare an asciicast render against a baseline svg."`
+		Extract extract.Cmd `cmd:"" help:"Extract the asciicast embedded in an exported svg."`
+		Info    info.Cmd    `cmd:"" help:"Show what a recording says about itself."`
+		Demo    demo.Cmd    `cmd:"" help:"Render a bundled recording to try termsvg out."`
 	}
 
 	ctx := kong.Parse(&cli,
 		kong.Name("termsvg"),
 		kong.Description("A cli tool for recording terminal sessions"),
-		kong.UsageOnError())
+		kong.UsageOnError(),
+		kong.Configuration(kong.JSON, configFile))
 	// Call the Run() method of the selected parsed command.
 	err := ctx.Run(&Context{Debug: cli.Debug})
 	ctx.FatalIfErrorf(err)
@@ -1,34 +1,77 @@
 package play
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/mrmarble/termsvg/pkg/asciicast"
+	"github.com/rs/zerolog/log"
 )
 
+// How often a followed file is checked for new events.
+const followInterval = 100 * time.Millisecond
+
 type Cmd struct {
 	File    string  `arg:"" type:"existingfile" help:"termsvg recording file"`
 	Speed   float64 `optional:"" short:"s" default:"1.0" help:"Playback speed (can be fractional)"`
 	IdleCap float64 `optional:"" short:"i" default:"-1.0" help:"Limit replayed terminal inactivity to max seconds. (-1 for unlimited)"` //nolint
+	Follow  bool    `optional:"" short:"f" help:"Keep playing events appended to the file, like tail -f"`
 }
 
 func (cmd *Cmd) Run() error {
-	return play(cmd.File, cmd.IdleCap, cmd.Speed)
+	return play(cmd.File, cmd.IdleCap, cmd.Speed, cmd.Follow)
 }
 
-func play(path string, idleCap, speed float64) error {
-	file, err := os.ReadFile(path)
+func play(path string, idleCap, speed float64, follow bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
 	if err != nil {
 		return err
 	}
 
-	records, err := asciicast.Unmarshal(file)
+	if follow {
+		// A recording still being written may end in a partial line, it is played once complete
+		data = data[:bytes.LastIndexByte(data, '\n')+1]
+	}
+
+	if len(bytes.TrimSpace(data)) > 0 {
+		records, err := asciicast.Unmarshal(data)
+		if err != nil {
+			return err
+		}
+
+		if records.DefaultSize {
+			log.Warn().Int("width", records.Header.Width).Int("height", records.Header.Height).
+				Msg("recording without a terminal size, using the default.")
+		}
+
+		replay(records, idleCap, speed)
+	}
+
+	if !follow {
+		return nil
+	}
+
+	_, err = file.Seek(int64(len(data)), io.SeekStart)
 	if err != nil {
 		return err
 	}
 
+	return tail(file, os.Stdout, followInterval, nil)
+}
+
+func replay(records *asciicast.Cast, idleCap, speed float64) {
 	records.ToRelativeTime()
 	records.CapRelativeTime(idleCap)
 	records.ToAbsoluteTime()
@@ -44,6 +87,44 @@ func play(path string, idleCap, speed float64) error {
 		time.Sleep(delay)
 		fmt.Print(record.EventData)
 	}
+}
+
+// tail prints the events appended to r as soon as their line is complete,
+// checking for more every interval until done is closed.
+func tail(r io.Reader, w io.Writer, interval time.Duration, done <-chan struct{}) error {
+	reader := bufio.NewReader(r)
+	line := ""
+
+	for {
+		chunk, err := reader.ReadString('\n')
+		line += chunk
 
-	return nil
+		if err == io.EOF {
+			select {
+			case <-done:
+				return nil
+			case <-time.After(interval):
+				continue
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		// The header is only there when following a file that was empty
+		if line != "" && line[0] != '{' {
+			var event asciicast.Event
+
+			err = json.Unmarshal([]byte(line), &event)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprint(w, event.EventData)
+		}
+
+		line = ""
+	}
 }
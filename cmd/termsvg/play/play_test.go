@@ -0,0 +1,83 @@
+package play
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is written by tail while the test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func TestTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.cast")
+	if err := os.WriteFile(path, []byte("{\"version\":2,\"width\":10,\"height\":2}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	writer, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+
+	var output syncBuffer
+
+	done := make(chan struct{})
+	errs := make(chan error)
+
+	go func() { errs <- tail(file, &output, time.Millisecond, done) }()
+
+	for _, chunk := range []string{
+		"[1.0,\"o\",\"one \"]\n",
+		"[2.0,\"o\",\"tw", // Written in two goes, played once complete
+		"o \"]\n[3.0,\"o\",\"three\"]\n",
+	} {
+		if _, err := writer.WriteString(chunk); err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for output.String() != "one two three" && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	close(done)
+
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	if output.String() != "one two three" {
+		t.Fatalf("unexpected output %q", output.String())
+	}
+}
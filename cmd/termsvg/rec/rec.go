@@ -17,8 +17,10 @@ import (
 
 type Cmd struct {
 	File          string `arg:"" type:"path" help:"filename/path to save the recording to"`
-	Command       string `short:"c" optional:"" env:"SHELL" help:"Specify command to record, defaults to $SHELL"`
+	Command       string `short:"c" optional:"" help:"Specify command to record, defaults to $SHELL"`
 	SkipFirstLine bool   `short:"s" help:"Skip the first line of recording"`
+	Shell         string `optional:"" help:"SHELL stored in the recording metadata, defaults to $SHELL"`
+	Term          string `optional:"" help:"TERM stored in the recording metadata, defaults to $TERM"`
 }
 
 const readSize = 1024
@@ -31,7 +33,7 @@ func (cmd *Cmd) Run() error {
 		log.Warn().Msg("Skipping the first line of recording.")
 	}
 
-	err := rec(cmd.File, cmd.Command, cmd.SkipFirstLine)
+	err := rec(cmd.File, cmd.Command, cmd.SkipFirstLine, cmd.Shell, cmd.Term)
 	if err != nil {
 		return err
 	}
@@ -42,24 +44,20 @@ func (cmd *Cmd) Run() error {
 	return nil
 }
 
-func rec(file, command string, skipFirstLine bool) error {
-	events, err := run(command, skipFirstLine)
+func rec(file, command string, skipFirstLine bool, shell, terminal string) error {
+	events, err := run(orShell(command), skipFirstLine)
 	if err != nil {
 		return err
 	}
 
-	rec := asciicast.New()
-
 	width, height, err := term.GetSize(int(os.Stdout.Fd()))
 	if err != nil {
 		return err
 	}
 
+	rec := newCast(events, command, shell, terminal)
 	rec.Header.Width = width
 	rec.Header.Height = height
-	rec.Header.Duration = events[len(events)-1].Time
-	rec.Events = events
-	rec.Compress()
 
 	js, err := rec.Marshal()
 	if err != nil {
@@ -74,6 +72,39 @@ func rec(file, command string, skipFirstLine bool) error {
 	return nil
 }
 
+// newCast builds the recording of events. Only a command given with -c is stored,
+// recording the shell is the default and says nothing about what was recorded.
+func newCast(events []asciicast.Event, command, shell, terminal string) *asciicast.Cast {
+	rec := asciicast.New()
+	rec.Header.Command = command
+	overrideEnv(rec, shell, terminal)
+	rec.Events = events
+	rec.RecomputeDuration()
+	rec.Compress()
+
+	return rec
+}
+
+// orShell returns command, or $SHELL when none was given.
+func orShell(command string) string {
+	if command == "" {
+		return os.Getenv("SHELL")
+	}
+
+	return command
+}
+
+// overrideEnv replaces the captured SHELL and TERM with the non empty values given.
+func overrideEnv(rec *asciicast.Cast, shell, terminal string) {
+	if shell != "" {
+		rec.Header.Env.Shell = shell
+	}
+
+	if terminal != "" {
+		rec.Header.Env.Term = terminal
+	}
+}
+
 // nolint
 func run(command string, skipFirstLine bool) ([]asciicast.Event, error) {
 	// Create arbitrary command.
@@ -113,49 +144,51 @@ func run(command string, skipFirstLine bool) ([]asciicast.Event, error) {
 		}
 	}()
 
+	return capture(ptmx, os.Stdout, skipFirstLine), nil
+}
+
+// capture copies src into dst until src fails or ends, or dst fails, stamping each chunk with
+// the time its read returned. The final chunk is kept even if it comes with the error.
+func capture(src io.Reader, dst io.Writer, skipFirstLine bool) []asciicast.Event {
 	var events []asciicast.Event
 
 	p := make([]byte, readSize)
-	baseTime := time.Now().UnixMicro()
+	baseTime := time.Now()
 
-	startTriggered := false
+	startTriggered := !skipFirstLine
 
 	for {
-		n, err := ptmx.Read(p)
-		event := asciicast.Event{
-			Time:      float64(time.Now().UnixMicro()-baseTime) / float64(time.Millisecond),
-			EventType: asciicast.Output, EventData: string(p[:n]),
-		}
-
-		if err != nil {
-			if err == io.EOF {
-				os.Stdout.Write(p[:n]) // should handle any remainding bytes.
-
-				events = append(events, event)
+		n, err := src.Read(p)
+		elapsed := time.Since(baseTime).Seconds()
+
+		if n > 0 {
+			_, writeErr := dst.Write(p[:n])
+			if writeErr != nil && err == nil {
+				// Nothing is shown anymore, the chunk read is still recorded
+				log.Error().Err(writeErr).Msg("error writing output, recording stopped.")
+				err = writeErr
 			}
 
-			break
-		}
-
-		os.Stdout.Write(p[:n])
-
-		// Skip the first line
-		if skipFirstLine {
-			if !startTriggered {
-				if strings.Contains(string(p[:n]), "\n") {
-					startTriggered = true
-					baseTime = time.Now().UnixMicro()
-					continue
-				} else {
-					continue
-				}
+			switch {
+			case startTriggered:
+				events = append(events, asciicast.Event{
+					Time:      elapsed,
+					EventType: asciicast.Output, EventData: string(p[:n]),
+				})
+			case strings.Contains(string(p[:n]), "\n"):
+				// Skip the first line
+				startTriggered = true
+				baseTime = time.Now()
 			}
 		}
 
-		events = append(events, event)
+		// On Linux the pty ends with EIO instead of EOF, both mean we are done
+		if err != nil {
+			break
+		}
 	}
 
-	return events, nil
+	return events
 }
 
 func handlePtySize(ptmx *os.File) chan os.Signal {
@@ -0,0 +1,172 @@
+package rec
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/mrmarble/termsvg/pkg/asciicast"
+)
+
+// fakePty hands out one chunk per read and returns the last one along with io.EOF.
+type fakePty struct {
+	chunks []string
+	delay  time.Duration
+}
+
+func (f *fakePty) Read(p []byte) (int, error) {
+	if len(f.chunks) == 0 {
+		return 0, io.EOF
+	}
+
+	time.Sleep(f.delay)
+
+	n := copy(p, f.chunks[0])
+	f.chunks = f.chunks[1:]
+
+	if len(f.chunks) == 0 {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func TestCapture(t *testing.T) {
+	delay := 10 * time.Millisecond
+	src := &fakePty{chunks: []string{"one", "two", "three"}, delay: delay}
+
+	var dst bytes.Buffer
+
+	events := capture(src, &dst, false)
+
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+
+	for i, event := range events {
+		if event.Time < float64(i+1)*delay.Seconds() {
+			t.Fatalf("event %d at %fs, expected at least %fs", i, event.Time, float64(i+1)*delay.Seconds())
+		}
+
+		if i > 0 && event.Time < events[i-1].Time {
+			t.Fatalf("event %d goes back in time", i)
+		}
+	}
+
+	if events[2].EventData != "three" {
+		t.Fatalf("final chunk was not recorded: %q", events[2].EventData)
+	}
+
+	if dst.String() != "onetwothree" {
+		t.Fatalf("unexpected output %q", dst.String())
+	}
+}
+
+func TestCaptureSkipFirstLine(t *testing.T) {
+	src := &fakePty{chunks: []string{"prompt", "\r\n", "one", "two"}}
+
+	events := capture(src, io.Discard, true)
+
+	if len(events) != 2 || events[0].EventData != "one" || events[1].EventData != "two" {
+		t.Fatalf("unexpected events %v", events)
+	}
+}
+
+// failingWriter accepts limit writes and fails the following ones.
+type failingWriter struct {
+	limit int
+}
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if f.limit == 0 {
+		return 0, io.ErrClosedPipe
+	}
+
+	f.limit--
+
+	return len(p), nil
+}
+
+func TestCaptureWriteError(t *testing.T) {
+	src := &fakePty{chunks: []string{"one", "two", "three", "four"}}
+
+	events := capture(src, &failingWriter{limit: 1}, false)
+
+	if len(events) != 2 || events[0].EventData != "one" || events[1].EventData != "two" {
+		t.Fatalf("unexpected events %v", events)
+	}
+
+	if len(src.chunks) != 2 {
+		t.Fatalf("kept reading after the write failed, %d chunks left", len(src.chunks))
+	}
+}
+
+func TestOverrideEnv(t *testing.T) {
+	t.Setenv("SHELL", "/bin/bash")
+	t.Setenv("TERM", "xterm")
+
+	tests := map[string]struct {
+		shell, term string
+		output      string
+	}{
+		"Captured":   {"", "", `"env":{"SHELL":"/bin/bash","TERM":"xterm"}`},
+		"Shell only": {"/bin/zsh", "", `"env":{"SHELL":"/bin/zsh","TERM":"xterm"}`},
+		"Both":       {"/bin/zsh", "xterm-256color", `"env":{"SHELL":"/bin/zsh","TERM":"xterm-256color"}`},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := asciicast.New()
+			overrideEnv(rec, tc.shell, tc.term)
+
+			js, err := rec.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Contains(js, []byte(tc.output)) {
+				t.Fatalf("%s not found in header:\n%s", tc.output, js)
+			}
+		})
+	}
+}
+
+func TestCommand(t *testing.T) {
+	t.Setenv("SHELL", "/bin/bash")
+
+	tests := map[string]struct {
+		args    []string
+		command string
+		run     string
+	}{
+		"Default": {[]string{"out.cast"}, "", "/bin/bash"},
+		"Flag":    {[]string{"out.cast", "-c", "htop"}, "htop", "htop"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var cmd Cmd
+
+			parser, err := kong.New(&cmd)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = parser.Parse(tc.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if run := orShell(cmd.Command); run != tc.run {
+				t.Fatalf("expected to run %q, got %q", tc.run, run)
+			}
+
+			rec := newCast(nil, cmd.Command, cmd.Shell, cmd.Term)
+			if rec.Header.Command != tc.command {
+				t.Fatalf("expected command %q, got %q", tc.command, rec.Header.Command)
+			}
+		})
+	}
+}
@@ -24,4 +24,5 @@ require (
 	github.com/google/go-cmp v0.6.0
 	github.com/rs/zerolog v1.32.0
 	golang.org/x/sys v0.17.0 // indirect
+	golang.org/x/text v0.3.8
 )
@@ -0,0 +1,57 @@
+package svg
+
+import (
+	"unicode/utf8"
+
+	"github.com/mrmarble/termsvg/pkg/css"
+)
+
+// Callout is a note shown over the terminal for a while, to point at something in a tutorial.
+type Callout struct {
+	Time     float64 `json:"time"`     // Seconds from the start it shows at
+	Duration float64 `json:"duration"` // Seconds it stays, 0 for until the end
+	Text     string  `json:"text"`
+	Row      int     `json:"row"` // Cell the note starts on, counting from 0
+	Col      int     `json:"col"`
+}
+
+// shownDuring reports whether the callout shows at some point from one second to another.
+func (co Callout) shownDuring(from, to float64) bool {
+	if co.Duration > 0 && co.Time+co.Duration <= from {
+		return false
+	}
+
+	return co.Time < to
+}
+
+// createCallouts draws the callouts shown while frame i is, at x like the frame.
+func (c *Canvas) createCallouts(x, i int) {
+	if c.opts.static {
+		return
+	}
+
+	from, to := c.Events[i].Time, c.Header.Duration
+	if i+1 < len(c.Events) {
+		to = c.Events[i+1].Time
+	}
+
+	// The last frame may end when it starts, it still shows
+	if to <= from {
+		to = from + 1
+	}
+
+	style := css.Rules{"fill": c.opts.Theme.Background, "font-family": "monospace", "font-size": "20px"}.String()
+
+	for _, callout := range c.opts.Callouts {
+		if !callout.shownDuring(from, to) {
+			continue
+		}
+
+		left := x + callout.Col*c.opts.ColWidth
+		top := callout.Row*c.opts.RowHeight - c.opts.RowHeight*3/4 //nolint:gomnd // Text hangs above its row position
+
+		c.Roundrect(left, top, utf8.RuneCountInString(callout.Text)*c.opts.ColWidth+padding, c.opts.RowHeight,
+			padding/4, padding/4, "fill:"+c.textColor()) //nolint:gomnd
+		c.Text(left+padding/2, callout.Row*c.opts.RowHeight, callout.Text, style)
+	}
+}
@@ -0,0 +1,53 @@
+package svg
+
+import "strings"
+
+// charsets keeps track of the G0 and G1 character sets and of Shift Out/Shift In
+// switching between them. vt10x only understands G0, so the active set is
+// rewritten as a G0 designation every time it changes.
+type charsets struct {
+	g0, g1  byte // Designated sets, 'B' is US ASCII and '0' line drawing
+	shifted bool // G1 is active
+}
+
+func newCharsets() *charsets {
+	return &charsets{g0: 'B', g1: 'B'}
+}
+
+func (c *charsets) rewrite(data string) string {
+	var out strings.Builder
+
+	for i := 0; i < len(data); i++ {
+		switch {
+		case data[i] == '\x1b' && i+2 < len(data) && (data[i+1] == '(' || data[i+1] == ')'):
+			if data[i+1] == '(' {
+				c.g0 = data[i+2]
+			} else {
+				c.g1 = data[i+2]
+			}
+
+			out.WriteString(c.designate())
+
+			i += 2
+		case data[i] == '\x0e': // SO
+			c.shifted = true
+			out.WriteString(c.designate())
+		case data[i] == '\x0f': // SI
+			c.shifted = false
+			out.WriteString(c.designate())
+		default:
+			out.WriteByte(data[i])
+		}
+	}
+
+	return out.String()
+}
+
+// designate returns the sequence selecting the active set as G0.
+func (c *charsets) designate() string {
+	if c.shifted {
+		return "\x1b(" + string(c.g1)
+	}
+
+	return "\x1b(" + string(c.g0)
+}
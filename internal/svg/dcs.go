@@ -0,0 +1,72 @@
+package svg
+
+import "strings"
+
+// dcs strips Device Control Strings (ESC P ... ESC \) like sixel images or terminal queries,
+// whose content is never shown. Strings can span events, the end of an unfinished one is
+// kept until it comes, one still unfinished when the recording ends is dropped. As on a VT,
+// CAN, SUB or the escape starting another sequence end them too. Escapes doubled inside
+// them, as tmux does, don't.
+type dcs struct {
+	// Replay the sequences wrapped by tmux (ESC P tmux; ... ESC \) instead of dropping them
+	tmux    bool
+	pending string // Unfinished string from the previous events
+}
+
+func (d *dcs) strip(data string) string {
+	data = d.pending + data
+	d.pending = ""
+
+	var out strings.Builder
+
+	for {
+		start := strings.Index(data, "\x1bP")
+		if start < 0 {
+			out.WriteString(data)
+			return out.String()
+		}
+
+		out.WriteString(data[:start])
+
+		body, end, ok := dcsBody(data[start+2:])
+		if !ok {
+			d.pending = data[start:]
+			return out.String()
+		}
+
+		if d.tmux && strings.HasPrefix(body, "tmux;") {
+			out.WriteString(strings.ReplaceAll(body[len("tmux;"):], "\x1b\x1b", "\x1b"))
+		}
+
+		data = data[start+2+end:]
+	}
+}
+
+// dcsBody returns the content of the string starting data, and where the data after it starts.
+// ok is false if data ends before the string does.
+func dcsBody(data string) (body string, end int, ok bool) {
+	for i := 0; i < len(data); i++ {
+		if data[i] == '\x18' || data[i] == '\x1a' { // CAN and SUB cancel the string
+			return data[:i], i + 1, true
+		}
+
+		if data[i] != '\x1b' {
+			continue
+		}
+
+		if i+1 == len(data) {
+			return "", 0, false // What follows the escape is in the next event
+		}
+
+		switch data[i+1] {
+		case '\\':
+			return data[:i], i + 2, true
+		case '\x1b':
+			i++ // Doubled escape, part of the content
+		default:
+			return data[:i], i, true // Another sequence starts, it is kept
+		}
+	}
+
+	return "", 0, false
+}
@@ -0,0 +1,71 @@
+package svg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/mrmarble/termsvg/pkg/asciicast"
+)
+
+// castNamespace identifies the element holding the source recording.
+const castNamespace = "https://github.com/mrmarble/termsvg"
+
+// ErrNoCast is returned by Extract for svgs exported without EmbedCast.
+var ErrNoCast = errors.New("no embedded asciicast found")
+
+var embeddedCast = regexp.MustCompile(`<termsvg:cast[^>]*>([^<]*)</termsvg:cast>`)
+
+// createEmbeddedCast writes source, gzipped and base64 encoded. It isn't wrapped
+// in <metadata> because minifiers drop it, renderers ignore unknown namespaces anyway.
+func (c *Canvas) createEmbeddedCast(source asciicast.Cast) error {
+	js, err := source.Marshal()
+	if err != nil {
+		return err
+	}
+
+	var compressed bytes.Buffer
+
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(js); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.Writer, "<termsvg:cast xmlns:termsvg=%q encoding=\"gzip+base64\">%s</termsvg:cast>\n",
+		castNamespace, base64.StdEncoding.EncodeToString(compressed.Bytes()))
+
+	return nil
+}
+
+// Extract returns the recording embedded in an svg exported with EmbedCast.
+func Extract(data []byte) (*asciicast.Cast, error) {
+	match := embeddedCast.FindSubmatch(data)
+	if match == nil {
+		return nil, ErrNoCast
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(string(match[1]))
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	return asciicast.Unmarshal(js)
+}
@@ -0,0 +1,75 @@
+package svg
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownFont is returned for an Options.Font missing from Fonts.
+var ErrUnknownFont = errors.New("unknown font")
+
+// defaultFonts is the font stack used without Options.Font.
+const defaultFonts = "Monaco,Consolas,Menlo,'Bitstream Vera Sans Mono','Powerline Symbols',monospace"
+
+// Font is a monospace font the svg can ask for, with the cell size it draws at 20px.
+// The font isn't embedded, viewers without it fall back to the default stack, whose
+// glyphs are stretched to fit cells of another width.
+type Font struct {
+	Family              string
+	ColWidth, RowHeight int
+}
+
+// Fonts are the fonts Options.Font can pick, by name.
+var Fonts = map[string]Font{
+	"cascadia-code":   {"'Cascadia Code'", 12, 24},
+	"fira-code":       {"'Fira Code'", 12, 25},
+	"jetbrains-mono":  {"'JetBrains Mono'", 12, 26},
+	"source-code-pro": {"'Source Code Pro'", 12, 25},
+	"ubuntu-mono":     {"'Ubuntu Mono'", 10, 22},
+}
+
+// FontNames returns the names of Fonts, sorted.
+func FontNames() []string {
+	names := make([]string, 0, len(Fonts))
+	for name := range Fonts {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// fontFamily returns the css font stack for the font name, the default one when empty.
+func fontFamily(name string) string {
+	if font, ok := Fonts[name]; ok {
+		return font.Family + "," + defaultFonts
+	}
+
+	return defaultFonts
+}
+
+// fontMetrics fills the cell size of opts from its font, unless given. Cells narrower or
+// wider than the default fonts' get GridAlign, so the fallback glyphs don't overlap.
+func fontMetrics(opts Options) (Options, error) {
+	if opts.Font == "" {
+		return opts, nil
+	}
+
+	font, ok := Fonts[opts.Font]
+	if !ok {
+		return opts, fmt.Errorf("%w %q, expected one of %s", ErrUnknownFont, opts.Font, strings.Join(FontNames(), ", "))
+	}
+
+	if opts.ColWidth <= 0 || opts.RowHeight <= 0 {
+		opts.ColWidth, opts.RowHeight = font.ColWidth, font.RowHeight
+	}
+
+	if opts.ColWidth != colWidth {
+		opts.GridAlign = true
+	}
+
+	return opts, nil
+}
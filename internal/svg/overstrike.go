@@ -0,0 +1,25 @@
+package svg
+
+import "regexp"
+
+// Printable character, backspace, printable character. Used by man and other
+// tools formatting for line printers.
+var overstrikes = regexp.MustCompile(`([^\x00-\x1f\x7f])\x08([^\x00-\x1f\x7f])`)
+
+// overstrike rewrites "X backspace X" as bold X and "_ backspace X" as underlined X.
+// Other overstrikes are kept, the terminal just draws the last character.
+func overstrike(data string) string {
+	return overstrikes.ReplaceAllStringFunc(data, func(match string) string {
+		sub := overstrikes.FindStringSubmatch(match)
+		under, char := sub[1], sub[2]
+
+		switch {
+		case under == char:
+			return "\x1b[1m" + char + "\x1b[22m"
+		case under == "_":
+			return "\x1b[4m" + char + "\x1b[24m"
+		default:
+			return match
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package svg
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SGR sequences with ITU T.416 colon separated sub-parameters, as emitted by
+// some terminals and editors for extended colors and underline styles.
+var colonSGRs = regexp.MustCompile(`\x1b\[([0-9;]*:[0-9:;]*)m`)
+
+// colonSGR rewrites colon separated SGR parameters with semicolons, the only form vt10x parses.
+// It would otherwise drop the whole sequence and reset the attributes.
+func colonSGR(data string) string {
+	return colonSGRs.ReplaceAllStringFunc(data, func(match string) string {
+		var params []string
+
+		for _, param := range strings.Split(colonSGRs.FindStringSubmatch(match)[1], ";") {
+			if param = semicolonParam(param); param != "" {
+				params = append(params, param)
+			}
+		}
+
+		// An empty SGR is a reset, not what the sequence asked for
+		if len(params) == 0 {
+			return ""
+		}
+
+		return "\x1b[" + strings.Join(params, ";") + "m"
+	})
+}
+
+// semicolonParam returns the semicolon form of an SGR parameter, empty if vt10x can't draw it.
+func semicolonParam(param string) string {
+	subs := strings.Split(param, ":")
+	for i := range subs {
+		if subs[i] == "" {
+			subs[i] = "0"
+		}
+	}
+
+	switch {
+	case len(subs) == 1:
+		return param
+	case subs[0] == "58": // Underline color
+		return ""
+	case (subs[0] == "38" || subs[0] == "48") && subs[1] == "5" && len(subs) == 3:
+		return strings.Join(subs, ";")
+	case (subs[0] == "38" || subs[0] == "48") && subs[1] == "2":
+		rgb := subs[2:]
+		if len(rgb) > 3 {
+			rgb = rgb[1:4] // Skip the color space id
+		}
+
+		if len(rgb) != 3 {
+			return ""
+		}
+
+		return subs[0] + ";2;" + strings.Join(rgb, ";")
+	case subs[0] == "4" && subs[1] == "0":
+		return "24"
+	default:
+		// Styles like curly underline (4:3) fall back to the plain attribute
+		return subs[0]
+	}
+}
@@ -0,0 +1,81 @@
+package svg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+
+	svg "github.com/ajstarks/svgo"
+	"github.com/mrmarble/termsvg/pkg/asciicast"
+	"github.com/mrmarble/termsvg/pkg/css"
+)
+
+// SideBySide draws left and right next to each other, split by a divider, for before and after demos.
+// Both share a timeline: the shorter recording holds its last frame until the longer one ends.
+// Each side is a complete svg embedded as an image, so their styles and animations don't mix.
+func SideBySide(left, right asciicast.Cast, output Output, opts Options) (Stats, error) {
+	leftSource, rightSource := left, right
+
+	left, leftOpts, err := prepare(left, opts)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	right, rightOpts, err := prepare(right, opts)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	// Same as MinDuration, the last frame is held until then
+	duration := left.Header.Duration
+	if right.Header.Duration > duration {
+		duration = right.Header.Duration
+	}
+	left.Header.Duration, right.Header.Duration = duration, duration
+
+	// The sides are drawn at their natural size, the whole image is scaled instead
+	leftOpts.Width, rightOpts.Width = 0, 0
+
+	var leftSVG, rightSVG bytes.Buffer
+
+	leftCanvas, err := createCanvas(svg.New(&leftSVG), left, leftSource, leftOpts)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	rightCanvas, err := createCanvas(svg.New(&rightSVG), right, rightSource, rightOpts)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	width := leftCanvas.paddedWidth() + padding + rightCanvas.paddedWidth()
+	height := leftCanvas.paddedHeight()
+	if rightCanvas.paddedHeight() > height {
+		height = rightCanvas.paddedHeight()
+	}
+
+	canvas := svg.New(output)
+	if opts.Width > 0 {
+		canvas.Start(opts.Width, height*opts.Width/width, fmt.Sprintf(`viewBox="0 0 %d %d"`, width, height))
+	} else {
+		canvas.Start(width, height)
+	}
+
+	canvas.Rect(0, 0, width, height, "fill:"+leftOpts.Theme.Background)
+	canvas.Image(0, 0, leftCanvas.paddedWidth(), leftCanvas.paddedHeight(), DataURI(leftSVG.Bytes()))
+	canvas.Line(leftCanvas.paddedWidth()+padding/2, 0, leftCanvas.paddedWidth()+padding/2, height,
+		css.Rules{"stroke": leftCanvas.textColor(), "stroke-width": "2"}.String())
+	canvas.Image(leftCanvas.paddedWidth()+padding, 0, rightCanvas.paddedWidth(), rightCanvas.paddedHeight(),
+		DataURI(rightSVG.Bytes()))
+	canvas.End()
+
+	return Stats{
+		Frames:     leftCanvas.drawn + rightCanvas.drawn,
+		Duplicates: leftCanvas.duplicates + rightCanvas.duplicates,
+	}, nil
+}
+
+// DataURI returns the svg as a link images can be drawn from, or pasted in html and markdown.
+func DataURI(image []byte) string {
+	return "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString(image)
+}
@@ -0,0 +1,118 @@
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+
+	svg "github.com/ajstarks/svgo"
+	"github.com/hinshun/vt10x"
+	"github.com/mrmarble/termsvg/pkg/asciicast"
+)
+
+// Storyboard draws each distinct screen of the recording as a static svg, captioned with its
+// position and how long it shows. Frames leaving the screen unchanged add their time to the
+// previous one, as with DropIdleFrames.
+func Storyboard(input asciicast.Cast, opts Options) ([][]byte, error) {
+	source := input
+	opts.DropIdleFrames = true
+
+	input, opts, err := prepareStill(input, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	caption := opts.Caption
+	boards := make([][]byte, 0, len(input.Events))
+
+	// Each board draws the screen as the events so far left it
+	term := vt10x.New(vt10x.WithSize(input.Header.Width, input.Header.Height))
+
+	for i, event := range input.Events {
+		if err := write(term, event); err != nil {
+			return nil, err
+		}
+
+		end := input.Header.Duration
+		if i+1 < len(input.Events) {
+			end = input.Events[i+1].Time
+		}
+
+		hold := fmt.Sprintf("%d/%d, shown %.2fs", i+1, len(input.Events), math.Max(end-event.Time, 0))
+		opts.Caption = strings.TrimPrefix(caption+"\n"+hold, "\n")
+
+		var out bytes.Buffer
+
+		if err := createStill(&out, input, source, opts, term); err != nil {
+			return nil, err
+		}
+		boards = append(boards, out.Bytes())
+	}
+
+	return boards, nil
+}
+
+// Poster draws the screen at the given second as a static svg, a preview of the animation.
+// A negative time picks the last frame with something on screen.
+func Poster(input asciicast.Cast, output Output, opts Options, at float64) error {
+	source := input
+
+	input, opts, err := prepareStill(input, opts)
+	if err != nil {
+		return err
+	}
+
+	term := vt10x.New(vt10x.WithSize(input.Header.Width, input.Header.Height))
+	data, shown := "", ""
+
+	for _, event := range input.Events {
+		if at >= 0 && event.Time > at {
+			break
+		}
+
+		data += event.EventData
+
+		if err := write(term, event); err != nil {
+			return err
+		}
+
+		if at >= 0 || strings.TrimSpace(term.String()) != "" {
+			shown = data
+		}
+	}
+
+	still := vt10x.New(vt10x.WithSize(input.Header.Width, input.Header.Height))
+	if err := write(still, asciicast.Event{EventType: asciicast.Output, EventData: shown}); err != nil {
+		return err
+	}
+
+	return createStill(output, input, source, opts, still)
+}
+
+// prepareStill is prepare for the canvases drawing a single frame.
+func prepareStill(input asciicast.Cast, opts Options) (asciicast.Cast, Options, error) {
+	opts.SeamlessLoop = false // Nothing loops
+
+	input, opts, err := prepare(input, opts)
+	if err != nil {
+		return input, opts, err
+	}
+
+	opts.Layout = LayoutTranslate
+	opts.EmbedCast = false // Once per image would be a lot
+	opts.static = true
+
+	return input, opts, nil
+}
+
+// createStill draws the screen of term, which was fed the output up to some event of cast.
+func createStill(output Output, cast, source asciicast.Cast, opts Options, term vt10x.Terminal) error {
+	cast.Events = []asciicast.Event{{Time: 0, EventType: asciicast.Output}}
+	cast.RecomputeDuration()
+	opts.screen = term
+
+	_, err := createCanvas(svg.New(output), cast, source, opts)
+
+	return err
+}
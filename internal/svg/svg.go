@@ -1,9 +1,17 @@
 package svg
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"html"
 	"io"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	svg "github.com/ajstarks/svgo"
 	"github.com/hinshun/vt10x"
@@ -11,6 +19,9 @@ import (
 	"github.com/mrmarble/termsvg/pkg/asciicast"
 	"github.com/mrmarble/termsvg/pkg/color"
 	"github.com/mrmarble/termsvg/pkg/css"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
 )
 
 type Canvas struct {
@@ -20,68 +31,605 @@ type Canvas struct {
 	width  int
 	height int
 	colors map[string]string
+	opts   Options
+	rows   int // Terminal rows drawn on the canvas
+	// Rows down to the last one that ever had content
+	usedRows int
+	// Row content to the id it was drawn with, for DeltaFrames
+	sharedRows map[string]string
+	duplicates int  // Frames drawn with <use>
+	drawn      int  // Frames drawn, fewer than the events once the Deadline passes
+	flat       bool // Text painted by the frames group instead of classes, with FlatColor
 }
 
 type Output interface {
 	io.Writer
 }
 
+// ErrSize is returned for recordings whose terminal has no cells.
+var ErrSize = errors.New("invalid terminal size")
+
+// ErrTerminal is returned when the terminal emulator fails to replay the recording.
+var ErrTerminal = errors.New("terminal emulator failed on the recording")
+
 const (
-	rowHeight  = 25
-	colWidth   = 12
-	padding    = 20
-	headerSize = 3
+	rowHeight    = 25
+	colWidth     = 12
+	padding      = 20
+	headerHeight = padding << 1 // Window title bar
+	// Some renderers give up on frames side by side past this width, they get stacked instead
+	maxTranslateWidth = 1000000
 )
 
-// If user passed custom background and text colors, use them
-var (
-	foregroundColorOverride = ""
-	backgroundColorOverride = ""
+// Text attributes from vt10x.Glyph.Mode, the library keeps them unexported.
+const (
+	attrUnderline = 1 << 1
+	attrBold      = 1 << 2
+	textModes     = attrUnderline | attrBold
+)
+
+// Window selects the decoration drawn around the terminal.
+type Window int
+
+const (
+	WindowMacOS   Window = iota // Rounded window with traffic light buttons
+	WindowMinimal               // Thin border without buttons
+	WindowWindows               // Square window with controls on the right
 )
 
-func Export(input asciicast.Cast, output Output, bgColor, textColor string, noWindow bool) {
-	// Set the custom foreground and background colors
-	foregroundColorOverride = textColor
-	backgroundColorOverride = bgColor
+// Layout selects how frames are laid out and animated.
+type Layout int
 
-	input.Compress() // to reduce the number of frames
+const (
+	LayoutTranslate Layout = iota // Frames side by side, slid into view
+	LayoutOpacity                 // Frames stacked, each shown in turn
+)
 
-	createCanvas(svg.New(output), input, noWindow)
+// Options tweaks how the cast is drawn.
+type Options struct {
+	Theme    Theme
+	NoWindow bool // Takes precedence over Window
+	Window   Window
+	Label    string // Text shown centered in the title bar
+	// Drop the bottom rows that stay empty for the whole recording
+	TrimBlankRows bool
+	MaxFrames     int // Upper bound of rendered frames, 0 for unlimited
+	// Compose decomposed characters (NFC) so they take a single cell
+	NormalizeUnicode bool
+	// Draw repeated frames once and reference them with <use>
+	DedupFrames bool
+	// Show the first frame until the pointer hovers the image
+	StartPaused bool
+	// Cell size in pixels, to match fonts with other proportions
+	ColWidth, RowHeight int
+	// Name of the font from Fonts asked for first, its cell size is used unless ColWidth and RowHeight are set
+	Font string
+	// Turn "X backspace X" into bold X and "_ backspace X" into underlined X
+	Overstrike bool
+	// Lighten text colors below this WCAG contrast ratio against the background they are drawn on, 0 to disable
+	MinContrast float64
+	// Pixel width of the image, the drawing is scaled to fit. 0 keeps the natural size
+	Width int
+	// Stacking frames keeps the drawing one frame wide, some viewers cope better with it
+	Layout Layout
+	// Room around the terminal rows, nil for the default of the window style
+	Padding *Padding
+	// Static text below the terminal, one line per row
+	Caption string
+	// Draw each distinct row once and reference it from the frames showing it again.
+	// Takes precedence over DedupFrames, which it covers
+	DeltaFrames bool
+	// End the animation holding the first frame, for as long as it shows at the start,
+	// so looping back to it doesn't jump
+	SeamlessLoop bool
+	// Store the recording in the svg, Extract gets it back
+	EmbedCast bool
+	// Bounds in seconds of the time each frame is shown, 0 for no bound.
+	// Frames closer than a browser can show get skipped otherwise
+	MinDwell, MaxDwell float64
+	// Seconds the animation lasts at least, the last frame is held until then. Keeps short recordings from flickering
+	MinDuration float64
+	// Draw everything ever printed as one tall static image instead of animating the viewport.
+	// Meant for line oriented output, full screen programs are drawn as if the terminal was that tall
+	FullScrollback bool
+	// Rows scrolled off the top kept by FullScrollback, the oldest are dropped past it. 0 for unlimited.
+	// A line wrapping takes a row per width it spans
+	ScrollbackLines int
+	// Stretch each run of text to its cells so glyphs stay on the grid with fonts of other widths.
+	// Wide glyphs get a run of their own so they don't push the text after them
+	GridAlign bool
+	// Merge frames that don't change the screen into the previous one, so idle periods are a single frame
+	DropIdleFrames bool
+	// Show the time elapsed since the start in the top right corner of each frame
+	ShowClock bool
+	// Colors replaced by others, as lowercase #rrggbb. Applies to text and cell backgrounds
+	ColorMap map[string]string
+	// Device control strings are dropped, replay the sequences tmux wrapped in them instead
+	TmuxPassthrough bool
+	// Move the content of each frame down so its last line sits on the bottom row, like a log being tailed
+	AnchorBottom bool
+	// Make the urls printed in the recording clickable links
+	Linkify bool
+	// Join runs of the same style separated by at most this many spaces, fewer <text> for a less exact layout.
+	// 0 keeps them apart
+	MergeGap int
+	// Notes drawn over the frames shown during their time
+	Callouts []Callout
+	// Paint the text of recordings using a single color and no backgrounds without css classes, smaller for plain output
+	FlatColor bool
+	// Playback speed, below 1 for slow motion. Times given in other options are of the sped up recording.
+	// 0 keeps the recorded speed
+	Speed float64
+	// Stop drawing frames past this time and end the animation on the last one drawn. Zero for no limit
+	Deadline time.Time
+
+	static bool           // A single frame without animation, set by the modes drawing one
+	screen vt10x.Terminal // Drawn as the single frame of a still, which then has no output to replay
 }
 
-func createCanvas(svg *svg.SVG, cast asciicast.Cast, noWindow bool) {
-	canvas := &Canvas{SVG: svg, Cast: cast, id: uniqueid.New(), colors: make(map[string]string)}
-	canvas.width = cast.Header.Width * colWidth
-	canvas.height = cast.Header.Height * rowHeight
+// Padding is the room around the terminal rows in pixels, below the title bar if there is one.
+// Text hangs above its row position, Top needs to leave room for the first row.
+type Padding struct {
+	Top, Right, Bottom, Left int
+}
 
-	parseCast(canvas)
-	canvas.Start(canvas.paddedWidth(), canvas.paddedHeight())
-	if !noWindow {
-		canvas.createWindow()
-		canvas.Group(fmt.Sprintf(`transform="translate(%d,%d)"`, padding, padding*headerSize))
-	} else {
-		if backgroundColorOverride == "" {
-			canvas.Rect(0, 0, canvas.paddedWidth(), canvas.paddedHeight(), "fill:#282d35")
-		} else {
-			canvas.Rect(0, 0, canvas.paddedWidth(), canvas.paddedHeight(), "fill:"+backgroundColorOverride)
+func defaultPadding(opts Options) Padding {
+	if hasTitleBar(opts) {
+		return Padding{Top: padding, Right: padding, Bottom: 0, Left: padding}
+	}
+
+	//nolint:gomnd
+	return Padding{Top: int(padding * 1.5), Right: padding, Bottom: int(padding * 1.5), Left: padding}
+}
+
+// Stats describes what an export drew.
+type Stats struct {
+	Frames     int // Frames of the animation
+	Duplicates int // Frames reusing a previous one, with DedupFrames
+}
+
+// Export draws input as an animated svg. The error wraps ErrTerminal when the recording
+// can't be replayed, what was written to output until then is not a valid svg.
+func Export(input asciicast.Cast, output Output, opts Options) (Stats, error) {
+	source := input
+
+	input, opts, err := prepare(input, opts)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	canvas, err := createCanvas(svg.New(output), input, source, opts)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	return Stats{Frames: canvas.drawn, Duplicates: canvas.duplicates}, nil
+}
+
+// Palette returns the colors the exported svg would use, along with their css class.
+func Palette(input asciicast.Cast, opts Options) ([]Swatch, error) {
+	input, opts, err := prepare(input, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	canvas := newCanvas(nil, input, opts)
+	if err := parseCast(canvas); err != nil {
+		return nil, err
+	}
+
+	return canvas.swatches(), nil
+}
+
+// prepare fills the option defaults and applies the event transformations they ask for.
+func prepare(input asciicast.Cast, opts Options) (asciicast.Cast, Options, error) {
+	if input.Header.Width <= 0 || input.Header.Height <= 0 {
+		return input, opts, fmt.Errorf("%w %dx%d", ErrSize, input.Header.Width, input.Header.Height)
+	}
+
+	opts, err := orDefaults(opts)
+	if err != nil {
+		return input, opts, err
+	}
+
+	retime(&input, opts)
+
+	if opts.FullScrollback {
+		err = scrollback(&input, opts.ScrollbackLines)
+		if err != nil {
+			return input, opts, err
 		}
-		//nolint:gomnd
-		canvas.Group(fmt.Sprintf(`transform="translate(%d,%d)"`, padding, int(padding*1.5)))
+
+		opts.TrimBlankRows = true
+		opts.Layout = LayoutTranslate
+		opts.static = true
+	}
+
+	rewrite(&input, opts)
+
+	if opts.DropIdleFrames {
+		// Once the data is final, so screens are compared as they will be drawn
+		err = dropIdleFrames(&input)
+		if err != nil {
+			return input, opts, err
+		}
+	}
+
+	hold(&input, opts)
+
+	return input, arrange(input, opts), nil
+}
+
+// orDefaults fills the theme and cell size left empty, and drops the options others cover.
+func orDefaults(opts Options) (Options, error) {
+	opts.Theme = opts.Theme.orDefault()
+
+	opts, err := fontMetrics(opts)
+	if err != nil {
+		return opts, err
+	}
+
+	if opts.ColWidth <= 0 || opts.RowHeight <= 0 {
+		opts.ColWidth, opts.RowHeight = colWidth, rowHeight
+	}
+
+	if opts.DeltaFrames {
+		opts.DedupFrames = false
+	}
+
+	return opts, nil
+}
+
+// retime keeps the output events, in order and at the speed asked, merging those that
+// show at once. The events are copied first, the caller's cast is left untouched.
+func retime(cast *asciicast.Cast, opts Options) {
+	cast.Events = append([]asciicast.Event(nil), cast.Events...)
+	cast.KeepOutput()
+	if clamped := cast.ClampTimes(); clamped > 0 {
+		log.Warn().Int("events", clamped).Msg("events going back in time, moved to the previous event.")
+	}
+
+	if opts.Speed != 1 {
+		cast.AdjustSpeed(opts.Speed)
+	}
+
+	cast.Compress() // to reduce the number of frames
+	cast.LimitEvents(opts.MaxFrames)
+}
+
+// rewrite turns the output into what vt10x draws as a terminal would.
+func rewrite(cast *asciicast.Cast, opts Options) {
+	deviceStrings := &dcs{tmux: opts.TmuxPassthrough}
+	charsets := newCharsets()
+
+	for i := range cast.Events {
+		data := colonSGR(charsets.rewrite(deviceStrings.strip(cast.Events[i].EventData)))
+
+		if opts.Overstrike {
+			data = overstrike(data)
+		}
+
+		if opts.NormalizeUnicode {
+			data = norm.NFC.String(data)
+		}
+
+		cast.Events[i].EventData = data
+	}
+}
+
+// hold sets how long the frames show, as bounded by the options.
+func hold(cast *asciicast.Cast, opts Options) {
+	if opts.MinDwell > 0 || opts.MaxDwell > 0 {
+		boundDwell(cast, opts.MinDwell, opts.MaxDwell)
+	}
+
+	if cast.Header.Duration < opts.MinDuration {
+		cast.Header.Duration = opts.MinDuration
+	}
+
+	if opts.SeamlessLoop {
+		cast.Header.Duration += loopHold(*cast)
+	}
+}
+
+// arrange returns opts with the padding around the frames, and the layout that fits them.
+func arrange(cast asciicast.Cast, opts Options) Options {
+	// Copied so the canvas never changes the caller's
+	pad := defaultPadding(opts)
+	if opts.Padding != nil {
+		pad = *opts.Padding
+	}
+	opts.Padding = &pad
+
+	drawingWidth := (cast.Header.Width*opts.ColWidth + pad.Left + pad.Right) * len(cast.Events)
+	if opts.Layout == LayoutTranslate && drawingWidth > maxTranslateWidth {
+		log.Warn().Int("frames", len(cast.Events)).Msg("recording too long to slide frames, stacking them instead.")
+
+		opts.Layout = LayoutOpacity
+	}
+
+	return opts
+}
+
+// boundDwell keeps the time each frame shows between minDwell and maxDwell seconds, 0 for no bound.
+// The last frame shows until the end of the recording, only maxDwell applies to it.
+func boundDwell(cast *asciicast.Cast, minDwell, maxDwell float64) {
+	tail := cast.Header.Duration
+	if len(cast.Events) > 0 {
+		tail -= cast.Events[len(cast.Events)-1].Time
+	}
+
+	if maxDwell > 0 && tail > maxDwell {
+		tail = maxDwell
+	}
+
+	cast.ToRelativeTime()
+	cast.FloorRelativeTime(minDwell)
+	cast.CapRelativeTime(maxDwell)
+	cast.ToAbsoluteTime()
+
+	cast.RecomputeDuration()
+	cast.Header.Duration += tail
+}
+
+// scrollback merges the events into a single frame drawn on a terminal tall enough that no line
+// scrolls off the top, the rows left unused are trimmed. With a limit of lines, the terminal is
+// only that taller and the oldest lines scroll off it.
+func scrollback(cast *asciicast.Cast, limit int) error {
+	data := ""
+	for _, event := range cast.Events {
+		data += event.EventData
+	}
+
+	lines := scrolls(data, cast.Header.Width)
+	if limit > 0 && lines > limit {
+		lines = limit
+	}
+
+	rows := cast.Header.Height
+	cast.Header.Height += lines
+
+	data, err := dropScrollback(data, cast.Header.Width, cast.Header.Height, rows)
+	if err != nil {
+		return err
+	}
+
+	cast.Events = []asciicast.Event{{Time: 0, EventType: asciicast.Output, EventData: data}}
+	cast.RecomputeDuration()
+
+	return nil
+}
+
+// scrolls bounds how many times data can scroll a terminal width columns wide: once per line
+// feed or index, and once per width bytes of a line as it wraps, no cell taking less than a byte.
+func scrolls(data string, width int) int {
+	n, column := 0, 0
+
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '\n', '\v', '\f':
+			n, column = n+1, 0
+		default:
+			if column++; column > width {
+				n, column = n+1, 1
+			}
+		}
+	}
+
+	return n + strings.Count(data, "\x1bD") + strings.Count(data, "\x1bE")
+}
+
+// clearScrollback erases the lines scrolled off the top. vt10x keeps none and ignores it.
+const clearScrollback = "\x1b[3J"
+
+// dropScrollback replaces each clearScrollback in data, drawn on a terminal of the given height,
+// by the sequences deleting the lines above the last rows ones, the screen of the recording.
+// The cursor is saved and moved up as many lines, so the output goes on where it was.
+func dropScrollback(data string, width, height, rows int) (string, error) {
+	if !strings.Contains(data, clearScrollback) {
+		return data, nil
+	}
+
+	term := vt10x.New(vt10x.WithSize(width, height))
+
+	var b strings.Builder
+
+	for i, part := range strings.Split(data, clearScrollback) {
+		if i > 0 {
+			if scrolled := term.Cursor().Y - rows + 1; scrolled > 0 {
+				part = fmt.Sprintf("\x1b7\x1b[H\x1b[%dM\x1b8\x1b[%dA", scrolled, scrolled) + part
+			}
+		}
+
+		if err := write(term, asciicast.Event{EventData: part}); err != nil {
+			return "", err
+		}
+
+		b.WriteString(part)
+	}
+
+	return b.String(), nil
+}
+
+// dropIdleFrames appends the events leaving the screen as it was to the previous one,
+// which then stays until the screen changes. Events must be in absolute time.
+func dropIdleFrames(cast *asciicast.Cast) error {
+	if len(cast.Events) == 0 {
+		return nil
+	}
+
+	term := vt10x.New(vt10x.WithSize(cast.Header.Width, cast.Header.Height))
+	events := cast.Events[:1]
+	last := ""
+
+	for i, event := range cast.Events {
+		if err := write(term, event); err != nil {
+			return err
+		}
+
+		current := screen(term)
+		switch {
+		case i == 0: // Already kept
+		case current == last:
+			events[len(events)-1].EventData += event.EventData
+		default:
+			events = append(events, event)
+		}
+
+		last = current
+	}
+
+	cast.Events = events
+
+	return nil
+}
+
+// write replays event on term. vt10x panics on some sequences instead of
+// returning an error, the panic is turned into one so it can be reported.
+func write(term io.Writer, event asciicast.Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w at %.2fs: %v", ErrTerminal, event.Time, r)
+		}
+	}()
+
+	if _, err := term.Write([]byte(event.EventData)); err != nil {
+		return fmt.Errorf("%w at %.2fs: %v", ErrTerminal, event.Time, err)
+	}
+
+	return nil
+}
+
+// screen returns what term shows, attributes included.
+func screen(term vt10x.Terminal) string {
+	cols, rows := term.Size()
+
+	var b strings.Builder
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			cell := term.Cell(col, row)
+			fmt.Fprintf(&b, "%c%d,%d,%d;", cell.Char, cell.FG, cell.BG, cell.Mode&textModes)
+		}
+	}
+
+	return b.String()
+}
+
+func newCanvas(svg *svg.SVG, cast asciicast.Cast, opts Options) *Canvas {
+	return &Canvas{SVG: svg, Cast: cast, id: uniqueid.New(), colors: make(map[string]string), opts: opts}
+}
+
+// createCanvas draws cast, source is the recording as given before prepare.
+func createCanvas(svg *svg.SVG, cast, source asciicast.Cast, opts Options) (*Canvas, error) {
+	canvas := newCanvas(svg, cast, opts)
+	canvas.width = cast.Header.Width * opts.ColWidth
+
+	if err := parseCast(canvas); err != nil {
+		return nil, err
+	}
+
+	// A single text color and no background, parseCast tracks both
+	canvas.flat = opts.FlatColor && len(canvas.colors) == 1
+
+	canvas.start()
+	if cast.Header.Command != "" {
+		canvas.Title(cast.Header.Command)
+	}
+	if opts.EmbedCast {
+		if err := canvas.createEmbeddedCast(source); err != nil {
+			return nil, err
+		}
+	}
+	canvas.createBackground()
+	canvas.Group(fmt.Sprintf(`transform="translate(%d,%d)"`, opts.Padding.Left, canvas.header()+opts.Padding.Top))
+	canvas.drawn = len(cast.Events)
+	if err := canvas.createBody(); err != nil {
+		return nil, err
 	}
-	canvas.addStyles()
-	canvas.createFrames()
-	canvas.Gend() // Transform
 	canvas.Gend() // Styles
+	canvas.createCaption()
+	canvas.Gend() // Transform
 	canvas.End()
+
+	return canvas, nil
 }
 
-func parseCast(c *Canvas) {
-	term := vt10x.New(vt10x.WithSize(c.Header.Width, c.Header.Height))
+// start sizes the canvas to the rows drawn and the caption, and opens the svg.
+func (c *Canvas) start() {
+	c.rows = c.Header.Height
+	if c.opts.TrimBlankRows && c.usedRows > 0 {
+		c.rows = c.usedRows
+	}
+	c.height = c.rows * c.opts.RowHeight
+	if c.opts.Caption != "" {
+		c.height += len(c.captionLines()) * c.opts.RowHeight
+	}
+
+	if c.opts.Width > 0 {
+		// Draw at the natural size and let the viewBox scale everything, text included
+		c.Start(c.opts.Width, c.paddedHeight()*c.opts.Width/c.paddedWidth(),
+			fmt.Sprintf(`viewBox="0 0 %d %d"`, c.paddedWidth(), c.paddedHeight()))
+	} else {
+		c.Start(c.paddedWidth(), c.paddedHeight())
+	}
+}
+
+// createBackground draws the window the terminal sits in, or its background alone.
+func (c *Canvas) createBackground() {
+	switch {
+	case c.opts.NoWindow:
+		c.Rect(0, 0, c.paddedWidth(), c.paddedHeight(), "fill:"+c.opts.Theme.Background)
+	case c.opts.Window == WindowMinimal:
+		c.Rect(0, 0, c.paddedWidth(), c.paddedHeight(),
+			css.Rules{"fill": c.opts.Theme.Background, "stroke": c.textColor(), "stroke-width": "2"}.String())
+	case c.opts.Window == WindowWindows:
+		c.createWindowsWindow()
+	default:
+		c.createWindow()
+	}
+}
+
+// createBody draws the styles and the frames, leaving the styles group open.
+func (c *Canvas) createBody() error {
+	if c.opts.Deadline.IsZero() {
+		c.addStyles()
+		return c.createFrames()
+	}
+
+	// The animation depends on how many frames make it in time, they go first
+	var err error
+	frames := c.capture(func() { err = c.createFrames() })
+	if err != nil {
+		return err
+	}
+	c.addStyles()
+	fmt.Fprint(c.Writer, frames)
+
+	return nil
+}
+
+func (c *Canvas) captionLines() []string {
+	return strings.Split(c.opts.Caption, "\n")
+}
+
+// createCaption draws the caption in the rows added below the terminal, out of the animation.
+func (c *Canvas) createCaption() {
+	if c.opts.Caption == "" {
+		return
+	}
+
+	style := css.Rules{"fill": c.textColor(), "font-family": "monospace", "font-size": "20px"}.String()
+	for i, line := range c.captionLines() {
+		c.Text(0, (c.rows+i)*c.opts.RowHeight, line, style)
+	}
+}
+
+func parseCast(c *Canvas) error {
+	term := c.terminal()
 
 	for _, event := range c.Events {
-		_, err := term.Write([]byte(event.EventData))
-		if err != nil {
-			panic(err)
+		if err := write(term, event); err != nil {
+			return err
 		}
 
 		for row := 0; row < c.Header.Height; row++ {
@@ -89,13 +637,28 @@ func parseCast(c *Canvas) {
 				cell := term.Cell(col, row)
 
 				c.getColors(cell)
+
+				if row >= c.usedRows && (visible(cell.Char) != ' ' || cell.BG != vt10x.DefaultBG) {
+					c.usedRows = row + 1
+				}
 			}
 		}
 	}
+
+	return nil
+}
+
+// terminal returns the terminal to write the events to, the screen of a still already shows them.
+func (c *Canvas) terminal() vt10x.Terminal {
+	if c.opts.screen != nil {
+		return c.opts.screen
+	}
+
+	return vt10x.New(vt10x.WithSize(c.Header.Width, c.Header.Height))
 }
 
 func (c *Canvas) getColors(cell vt10x.Glyph) {
-	fg := color.GetColor(cell.FG)
+	fg := c.textKey(cell.FG, cell.BG)
 
 	if _, ok := c.colors[fg]; !ok {
 		c.colors[fg] = c.id.String()
@@ -112,102 +675,622 @@ func (c *Canvas) getColors(cell vt10x.Glyph) {
 }
 
 func (c *Canvas) paddedWidth() int {
-	return c.width + (padding << 1)
+	return c.width + c.opts.Padding.Left + c.opts.Padding.Right
 }
 
 func (c *Canvas) paddedHeight() int {
-	return c.height + (padding * headerSize)
+	return c.height + c.header() + c.opts.Padding.Top + c.opts.Padding.Bottom
+}
+
+// header returns the height of the window title bar, if drawn.
+func (c *Canvas) header() int {
+	if !hasTitleBar(c.opts) {
+		return 0
+	}
+
+	return headerHeight
+}
+
+func hasTitleBar(opts Options) bool {
+	return !opts.NoWindow && (opts.Window == WindowMacOS || opts.Window == WindowWindows)
 }
 
 func (c *Canvas) createWindow() {
 	windowRadius := 5
 	buttonRadius := 7
-	buttonColors := [3]string{"#ff5f58", "#ffbd2e", "#18c132"}
 
-	// If the user has specified a background color, use that instead of the default
-	if backgroundColorOverride != "" {
-		c.Roundrect(0, 0, c.paddedWidth(), c.paddedHeight(), windowRadius, windowRadius, "fill:"+backgroundColorOverride)
-	} else {
-		c.Roundrect(0, 0, c.paddedWidth(), c.paddedHeight(), windowRadius, windowRadius, "fill:#282d35")
+	c.Roundrect(0, 0, c.paddedWidth(), c.paddedHeight(), windowRadius, windowRadius, "fill:"+c.opts.Theme.Background)
+	if c.opts.Theme.titleBar() != c.opts.Theme.Background {
+		// Rounded on top only, the rect squares the bottom corners off
+		c.Roundrect(0, 0, c.paddedWidth(), headerHeight, windowRadius, windowRadius, "fill:"+c.opts.Theme.titleBar())
+		c.Rect(0, windowRadius, c.paddedWidth(), headerHeight-windowRadius, "fill:"+c.opts.Theme.titleBar())
+	}
+
+	for i := range c.opts.Theme.Buttons {
+		c.Circle((i*(padding+buttonRadius/2))+padding, padding, buttonRadius, fmt.Sprintf("fill:%s", c.opts.Theme.Buttons[i]))
+	}
+
+	c.createLabel(len(c.opts.Theme.Buttons)*(padding+buttonRadius/2) + padding)
+}
+
+// createWindowsWindow draws a square window with minimize, maximize and close on the right.
+func (c *Canvas) createWindowsWindow() {
+	controlWidth := padding << 1
+	iconSize := padding / 2 //nolint:gomnd
+	stroke := css.Rules{"fill": "none", "stroke": c.textColor(), "stroke-width": "1"}.String()
+
+	c.Rect(0, 0, c.paddedWidth(), c.paddedHeight(), "fill:"+c.opts.Theme.Background)
+	if c.opts.Theme.titleBar() != c.opts.Theme.Background {
+		c.Rect(0, 0, c.paddedWidth(), headerHeight, "fill:"+c.opts.Theme.titleBar())
+	}
+
+	controls := []func(x, y int){
+		func(x, y int) { c.Line(x, y+iconSize/2, x+iconSize, y+iconSize/2, stroke) }, // Minimize
+		func(x, y int) { c.Rect(x, y, iconSize, iconSize, stroke) },                  // Maximize
+		func(x, y int) { // Close
+			c.Line(x, y, x+iconSize, y+iconSize, stroke)
+			c.Line(x, y+iconSize, x+iconSize, y, stroke)
+		},
+	}
+
+	for i, draw := range controls {
+		left := c.paddedWidth() - (len(controls)-i)*controlWidth
+		draw(left+(controlWidth-iconSize)/2, padding-iconSize/2)
+	}
+
+	c.createLabel(len(controls) * controlWidth)
+}
+
+// createLabel draws the label centered in the title bar, leaving reserved pixels free on both sides.
+func (c *Canvas) createLabel(reserved int) {
+	if c.opts.Label == "" {
+		return
+	}
+
+	// Keep the same room on both sides so the label stays centered
+	label := ellipsize(c.opts.Label, (c.paddedWidth()-reserved*2)/colWidth)
+
+	c.Text(c.paddedWidth()/2, padding, label, `text-anchor="middle"`, `dominant-baseline="middle"`,
+		css.Rules{"fill": c.textColor(), "font-family": "monospace", "font-size": "20px"}.String())
+}
+
+// defaultFG is the colors key of text drawn with the terminal default color.
+// It is kept apart from the palette light grey that vt10x paints it with,
+// so the theme can change the first without touching text colored with SGR 37.
+const defaultFG = "fg"
+
+// fgKey returns the colors key of the text color fg.
+func fgKey(fg vt10x.Color) string {
+	if fg == vt10x.DefaultFG {
+		return defaultFG
+	}
+
+	return color.GetColor(fg)
+}
+
+// bgSeparator splits the key of text over a background of its own, see textKey.
+const bgSeparator = "/"
+
+// textKey returns the colors key of text drawn in fg over bg. With MinContrast the text over
+// a background of its own, inverse video included, gets a color apart adjusted against it.
+func (c *Canvas) textKey(fg, bg vt10x.Color) string {
+	if c.opts.MinContrast <= 0 || bg == vt10x.DefaultBG {
+		return fgKey(fg)
 	}
 
-	for i := range buttonColors {
-		c.Circle((i*(padding+buttonRadius/2))+padding, padding, buttonRadius, fmt.Sprintf("fill:%s", buttonColors[i]))
+	return fgKey(fg) + bgSeparator + color.GetColor(bg)
+}
+
+// textColor returns the color used for the default terminal text,
+// the theme Foreground or else palette 7 like vt10x.
+func (c *Canvas) textColor() string {
+	if c.opts.Theme.Foreground != "" {
+		return c.opts.Theme.Foreground
 	}
+
+	return color.GetColor(vt10x.DefaultFG)
+}
+
+// ellipsize cuts text to at most limit characters, marking the cut with an ellipsis.
+func ellipsize(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+
+	if limit < 1 {
+		return ""
+	}
+
+	return string(runes[:limit-1]) + "…"
 }
 
 func (c *Canvas) addStyles() {
-	c.Gstyle(css.Rules{
-		"animation-duration":        fmt.Sprintf("%.2fs", c.Header.Duration),
-		"animation-iteration-count": "infinite",
-		"animation-name":            "k",
-		"animation-timing-function": "steps(1,end)",
-		"font-family":               "Monaco,Consolas,Menlo,'Bitstream Vera Sans Mono','Powerline Symbols',monospace",
-		"font-size":                 "20px",
-	}.String())
+	rules := css.Rules{
+		"font-family": fontFamily(c.opts.Font),
+		"font-size":   "20px",
+	}
+	if c.opts.Layout == LayoutTranslate && !c.opts.static {
+		rules["animation-duration"] = fmt.Sprintf("%.2fs", c.Header.Duration)
+		rules["animation-iteration-count"] = "infinite"
+		rules["animation-name"] = "k"
+		rules["animation-timing-function"] = "steps(1,end)"
 
+		if c.opts.StartPaused {
+			rules["animation-play-state"] = "paused"
+		}
+	}
 	// Foreground color gets set here
 	colors := css.Blocks{}
-	for color, class := range c.colors {
-		colors = append(colors, css.Block{Selector: fmt.Sprintf(".%s", class), Rules: css.Rules{"fill": color}})
+	for _, swatch := range c.swatches() {
+		if c.flat {
+			rules["fill"] = swatch.Hex
+			continue
+		}
+
+		colors = append(colors, css.Block{Selector: fmt.Sprintf(".%s", swatch.Class), Rules: css.Rules{"fill": swatch.Hex}})
 	}
+	c.Gstyle(rules.String())
 
-	styles := generateKeyframes(c.Cast, int32(c.paddedWidth()))
-	// If custom colors have been provided, use them instead
-	if foregroundColorOverride != "" {
-		styles += fmt.Sprintf(".a{fill:%s}", foregroundColorOverride)
-	} else {
-		styles += colors.String()
+	// The frames drawn, the last one stays until the end if the others didn't make it
+	shown := c.Cast
+	shown.Events = shown.Events[:c.drawn]
+
+	styles := ""
+	switch {
+	case c.opts.static:
+		// A single frame, nothing to animate
+	case c.opts.Layout == LayoutOpacity:
+		styles = generateFadeKeyframes(shown, c.opts.SeamlessLoop)
+	default:
+		styles = generateKeyframes(shown, int32(c.paddedWidth()), c.opts.SeamlessLoop)
+	}
+	styles += colors.String()
+
+	if c.opts.StartPaused {
+		// Inline styles only lose against !important
+		styles += css.Block{Selector: "svg:hover g", Rules: css.Rules{"animation-play-state": "running!important"}}.String()
 	}
 	c.Style("text/css", styles)
 }
 
-func (c *Canvas) createFrames() {
-	term := vt10x.New(vt10x.WithSize(c.Header.Width, c.Header.Height))
+// Swatch is a color of the svg and the css class that paints with it.
+type Swatch struct {
+	Hex   string `json:"hex"`
+	Class string `json:"class"`
+}
+
+// swatches lists the colors found by parseCast, sorted by class.
+func (c *Canvas) swatches() []Swatch {
+	swatches := make([]Swatch, 0, len(c.colors))
+
+	for key, class := range c.colors {
+		// Background filters are tracked here too but have no class
+		if class == "" {
+			continue
+		}
+
+		hex, bg := key, c.opts.Theme.Background
+		if i := strings.Index(key, bgSeparator); i >= 0 {
+			hex, bg = key[:i], c.mapColor(key[i+1:])
+		}
+
+		// The theme only replaces the terminal default text color, not an explicit palette 7
+		if hex == defaultFG {
+			hex = c.textColor()
+		}
+
+		hex = c.mapColor(hex)
+
+		if c.opts.MinContrast > 0 {
+			hex = color.EnsureContrast(hex, bg, c.opts.MinContrast)
+		}
+
+		swatches = append(swatches, Swatch{Hex: hex, Class: class})
+	}
+
+	// Map iteration is random, sort to get the same svg on every export
+	sort.Slice(swatches, func(i, j int) bool { return swatches[i].Class < swatches[j].Class })
+
+	return swatches
+}
+
+func (c *Canvas) createFrames() error {
+	term := c.terminal()
+	seen := make(map[string]int) // Frame content to the index of the first frame drawing it
 
 	for i, event := range c.Events {
-		_, err := term.Write([]byte(event.EventData))
-		if err != nil {
-			panic(err)
+		if i > 0 && !c.opts.Deadline.IsZero() && time.Now().After(c.opts.Deadline) {
+			log.Warn().Int("frames", i).Int("skipped", len(c.Events)-i).
+				Msg("out of time, the animation ends on the last frame drawn.")
+
+			c.drawn = i
+
+			return nil
 		}
 
+		if err := write(term, event); err != nil {
+			return err
+		}
+
+		if c.opts.Layout == LayoutOpacity {
+			c.createFadingFrame(term, i, seen)
+			continue
+		}
+
+		// Next to the frame rather than in it, so they don't get in the way of dedup
+		c.createClock(c.paddedWidth()*i, event.Time)
+		c.createSlidingFrame(term, i, seen)
+		c.createCallouts(c.paddedWidth()*i, i)
+	}
+
+	return nil
+}
+
+// createSlidingFrame draws frame i next to the previous one, the animation slides them into view.
+func (c *Canvas) createSlidingFrame(term vt10x.Terminal, i int, seen map[string]int) {
+	if !c.opts.DedupFrames {
 		c.Gtransform(fmt.Sprintf("translate(%d)", c.paddedWidth()*i))
+		c.createFrameRows(term)
+		c.Gend()
 
-		for row := 0; row < c.Header.Height; row++ {
-			frame := ""
-			lastColor := term.Cell(0, row).FG
-			lastColummn := 0
+		return
+	}
 
-			for col := 0; col < c.Header.Width; col++ {
-				cell := term.Cell(col, row)
-				c.addBG(cell.BG)
+	content := c.captureRows(term)
+	if first, ok := seen[content]; ok {
+		// Reuse the first frame, moved to where this one should be
+		c.Use(c.paddedWidth()*(i-first), 0, fmt.Sprintf("#f%d", first))
+		c.duplicates++
 
-				if cell.Char == ' ' || cell.FG != lastColor {
-					if frame != "" {
-						c.Text(lastColummn*colWidth,
-							row*rowHeight, frame, fmt.Sprintf(`class="%s"`, c.colors[color.GetColor(lastColor)]), c.applyBG(cell.BG))
+		return
+	}
 
-						frame = ""
-					}
+	seen[content] = i
 
-					if cell.Char == ' ' {
-						lastColummn = col + 1
-						continue
-					}
-					lastColor = cell.FG
-					lastColummn = col
+	c.Group(fmt.Sprintf(`id="f%d"`, i), fmt.Sprintf(`transform="translate(%d)"`, c.paddedWidth()*i))
+	fmt.Fprint(c.Writer, content)
+	c.Gend()
+}
 
-				}
+// createFadingFrame draws frame i on top of the others, visible only while it is the current one.
+func (c *Canvas) createFadingFrame(term vt10x.Terminal, i int, seen map[string]int) {
+	rules := css.Rules{
+		"animation": fmt.Sprintf("o%d %.2fs steps(1,end) infinite", i, c.Header.Duration),
+	}
+	if c.opts.StartPaused {
+		rules["animation-play-state"] = "paused"
+	}
+	c.Gstyle(rules.String())
+	c.createClock(0, c.Events[i].Time)
+
+	if c.opts.DedupFrames {
+		c.createDedupFrame(term, i, seen)
+	} else {
+		c.createFrameRows(term)
+	}
+
+	// Over the frame, in the group fading it
+	c.createCallouts(0, i)
+	c.Gend()
+}
+
+// createDedupFrame draws frame i in place, or reuses the first frame with the same content.
+func (c *Canvas) createDedupFrame(term vt10x.Terminal, i int, seen map[string]int) {
+	content := c.captureRows(term)
+	if first, ok := seen[content]; ok {
+		// The animation lives on the wrapping group so the copy doesn't bring the original's
+		c.Use(0, 0, fmt.Sprintf("#f%d", first))
+		c.duplicates++
+
+		return
+	}
+
+	seen[content] = i
+
+	c.Gid(fmt.Sprintf("f%d", i))
+	fmt.Fprint(c.Writer, content)
+	c.Gend()
+}
+
+// createClock draws the elapsed time, right aligned on the terminal at x, or left aligned
+// away from the controls of the windows style. It sits in the title bar if there is one,
+// above the first row otherwise.
+// Stills have no time passing to show.
+func (c *Canvas) createClock(x int, elapsed float64) {
+	if !c.opts.ShowClock || c.opts.static {
+		return
+	}
+
+	y := -c.opts.Padding.Top / 2 //nolint:gomnd
+	if hasTitleBar(c.opts) {
+		y = -c.opts.Padding.Top - headerHeight + padding
+	}
 
-				frame += string(cell.Char)
+	// The windows style has its controls on the right, the label leaves as much room on the left
+	anchor := "end"
+	if c.opts.Window == WindowWindows && !c.opts.NoWindow {
+		anchor = "start"
+	} else {
+		x += c.width
+	}
+
+	seconds := int(elapsed)
+	c.Text(x, y, fmt.Sprintf("%d:%02d", seconds/60, seconds%60), //nolint:gomnd
+		fmt.Sprintf(`text-anchor=%q`, anchor), `dominant-baseline="middle"`,
+		css.Rules{"fill": c.textColor(), "font-size": "14px"}.String())
+}
+
+// captureRows returns the output of createRows instead of writing it.
+func (c *Canvas) captureRows(term vt10x.Terminal) string {
+	return c.capture(func() { c.createRows(term) })
+}
+
+// capture returns what draw writes instead of writing it.
+func (c *Canvas) capture(draw func()) string {
+	out := c.Writer
+	defer func() { c.Writer = out }()
+
+	buf := new(bytes.Buffer)
+	c.Writer = buf
+	draw()
+
+	return buf.String()
+}
+
+func (c *Canvas) createFrameRows(term vt10x.Terminal) {
+	if c.opts.DeltaFrames {
+		c.createSharedRows(term)
+		return
+	}
+
+	c.createRows(term)
+}
+
+// createSharedRows draws the rows not seen in previous frames and references the others.
+func (c *Canvas) createSharedRows(term vt10x.Terminal) {
+	if c.sharedRows == nil {
+		c.sharedRows = make(map[string]string)
+	}
+
+	offset := c.anchor(term)
+
+	for row := 0; row < c.rows; row++ {
+		content := c.capture(func() { c.createRow(term, row, 0) })
+		if content == "" {
+			continue
+		}
+
+		id, ok := c.sharedRows[content]
+		if !ok {
+			id = fmt.Sprintf("r%d", len(c.sharedRows))
+			c.sharedRows[content] = id
+
+			c.Def()
+			c.Gid(id)
+			fmt.Fprint(c.Writer, content)
+			c.Gend()
+			c.DefEnd()
+		}
+
+		c.Use(0, (row+offset)*c.opts.RowHeight, "#"+id)
+	}
+}
+
+func (c *Canvas) createRows(term vt10x.Terminal) {
+	offset := c.anchor(term)
+
+	for row := 0; row < c.rows-offset; row++ {
+		c.createRow(term, row, (row+offset)*c.opts.RowHeight)
+	}
+}
+
+// anchor returns how many rows the frame moves down with AnchorBottom.
+func (c *Canvas) anchor(term vt10x.Terminal) int {
+	if !c.opts.AnchorBottom {
+		return 0
+	}
+
+	for row := c.rows - 1; row >= 0; row-- {
+		for col := 0; col < c.Header.Width; col++ {
+			cell := term.Cell(col, row)
+			if visible(cell.Char) != ' ' || cell.BG != vt10x.DefaultBG {
+				return c.rows - 1 - row
 			}
+		}
+	}
+
+	return 0
+}
+
+// createRow draws the runs of text in row at height y.
+func (c *Canvas) createRow(term vt10x.Terminal, row, y int) {
+	frame := ""
+	lastColor := term.Cell(0, row).FG
+	lastBG := term.Cell(0, row).BG
+	lastMode := term.Cell(0, row).Mode & textModes
+	lastColummn := 0
+	lastWide := false
+	links := c.links(term, row)
+
+	for col := 0; col < c.Header.Width; col++ {
+		cell := term.Cell(col, row)
+		cell.Char = visible(cell.Char)
+		c.addBG(cell.BG)
+
+		// A wide glyph is drawn on its own, the text after it starts again on its column
+		wide := c.opts.GridAlign && isWide(cell.Char)
 
-			if strings.TrimSpace(frame) != "" {
-				c.Text(lastColummn*colWidth, row*rowHeight, frame, fmt.Sprintf(`class="%s"`, c.colors[color.GetColor(lastColor)]))
+		if cell.Char == ' ' && frame != "" && !lastWide {
+			if gap := c.mergeGap(term, row, col, lastColor, lastBG, lastMode, links); gap > 0 {
+				frame += strings.Repeat(" ", gap)
+				col += gap - 1
+
+				continue
 			}
 		}
-		c.Gend()
+
+		if cell.Char == ' ' || cell.FG != lastColor || cell.BG != lastBG || cell.Mode&textModes != lastMode ||
+			wide || lastWide || links[col] != links[lastColummn] {
+			// The run is drawn with its own attributes, not the ones of the cell ending it
+			if text := trimRun(frame, lastBG); text != "" {
+				c.createRun(lastColummn*c.opts.ColWidth, y, text, links[lastColummn],
+					c.textAttrs(text, lastColor, lastBG, lastMode), c.applyBG(lastBG))
+			}
+			frame = ""
+
+			if cell.Char == ' ' {
+				lastColummn = col + 1
+				continue
+			}
+			lastColor = cell.FG
+			lastBG = cell.BG
+			lastMode = cell.Mode & textModes
+			lastColummn = col
+
+		}
+
+		frame += string(cell.Char)
+		lastWide = wide
+	}
+
+	c.createLastRun(frame, lastColummn*c.opts.ColWidth, y, links[lastColummn], lastColor, lastBG, lastMode)
+}
+
+// createLastRun draws the run ending row, what is left once its trailing blanks are trimmed.
+func (c *Canvas) createLastRun(frame string, x, y int, href string, fg, bg vt10x.Color, mode int16) {
+	frame = trimRun(frame, bg)
+	if strings.TrimSpace(frame) == "" {
+		return
 	}
+
+	attrs := []string{c.textAttrs(frame, fg, bg, mode)}
+	if filter := c.applyBG(bg); filter != "" {
+		attrs = append(attrs, filter)
+	}
+
+	c.createRun(x, y, frame, href, attrs...)
+}
+
+// mergeGap returns how many spaces from col, where the run ends, separate it from the next run of the same style.
+// 0 when there are more than MergeGap or the next run looks different.
+func (c *Canvas) mergeGap(term vt10x.Terminal, row, col int, fg, bg vt10x.Color, mode int16, links map[int]string) int {
+	href := links[col-1]
+
+	for gap := 0; gap <= c.opts.MergeGap && col+gap < c.Header.Width; gap++ {
+		cell := term.Cell(col+gap, row)
+		if cell.BG != bg || links[col+gap] != href {
+			return 0
+		}
+
+		if visible(cell.Char) == ' ' {
+			continue
+		}
+
+		if cell.FG != fg || cell.Mode&textModes != mode || (c.opts.GridAlign && isWide(cell.Char)) {
+			return 0
+		}
+
+		return gap
+	}
+
+	return 0
+}
+
+// trimRun drops the spaces ending a run over the default background, they draw nothing.
+// Plain spaces already end runs, these are the other unicode ones such as no-break spaces.
+func trimRun(text string, bg vt10x.Color) string {
+	if bg != vt10x.DefaultBG {
+		return text
+	}
+
+	return strings.TrimRightFunc(text, unicode.IsSpace)
+}
+
+// createRun draws a run of text, inside a link to href unless it is empty.
+func (c *Canvas) createRun(x, y int, text, href string, attrs ...string) {
+	if href == "" {
+		c.Text(x, y, text, attrs...)
+		return
+	}
+
+	// svgo writes the href as is
+	c.Link(html.EscapeString(href), href)
+	c.Text(x, y, text, attrs...)
+	c.LinkEnd()
+}
+
+// Urls found by Linkify, trailing punctuation is more likely the sentence's.
+var urls = regexp.MustCompile(`https?://[^\s"'<>]*[^\s"'<>.,;:!?)\]}]`)
+
+// links returns the url covering each column of row, nil without Linkify.
+func (c *Canvas) links(term vt10x.Terminal, row int) map[int]string {
+	if !c.opts.Linkify {
+		return nil
+	}
+
+	cells := make([]rune, c.Header.Width)
+	for col := range cells {
+		cells[col] = visible(term.Cell(col, row).Char)
+	}
+
+	text := string(cells)
+	links := make(map[int]string)
+
+	for _, loc := range urls.FindAllStringIndex(text, -1) {
+		start := utf8.RuneCountInString(text[:loc[0]])
+		for col := start; col < start+utf8.RuneCountInString(text[loc[0]:loc[1]]); col++ {
+			links[col] = text[loc[0]:loc[1]]
+		}
+	}
+
+	return links
+}
+
+// visible returns r, or a space for control and zero width characters that would
+// otherwise end up in the text. vt10x keeps some of them, like C1 controls.
+func visible(r rune) rune {
+	if !unicode.IsGraphic(r) {
+		return ' '
+	}
+
+	return r
+}
+
+// isWide reports whether r takes two columns in a terminal font.
+// vt10x gives it a single cell, which the glyph overflows.
+func isWide(r rune) bool {
+	kind := width.LookupRune(r).Kind()
+
+	return kind == width.EastAsianWide || kind == width.EastAsianFullwidth
+}
+
+// textAttrs returns the attributes of the run text drawn with the given colors and mode.
+func (c *Canvas) textAttrs(text string, fg, bg vt10x.Color, mode int16) string {
+	attrs := ""
+	if !c.flat {
+		attrs = fmt.Sprintf(`class="%s"`, c.colors[c.textKey(fg, bg)])
+	}
+
+	// A single glyph has no spacing to adjust, it already starts on its column
+	if cells := utf8.RuneCountInString(text); c.opts.GridAlign && cells > 1 {
+		attrs += fmt.Sprintf(` textLength="%d" lengthAdjust="spacing"`, cells*c.opts.ColWidth)
+	}
+
+	if mode&attrBold != 0 {
+		attrs += ` font-weight="bold"`
+	}
+
+	// Merged runs can have spaces in a row, which svg collapses otherwise
+	if strings.Contains(text, "  ") {
+		attrs += ` xml:space="preserve"`
+	}
+
+	if mode&attrUnderline != 0 {
+		attrs += ` text-decoration="underline"`
+	}
+
+	return strings.TrimSpace(attrs)
 }
 
 func (c *Canvas) addBG(bg vt10x.Color) {
@@ -215,7 +1298,7 @@ func (c *Canvas) addBG(bg vt10x.Color) {
 		if _, ok := c.colors[fmt.Sprint(bg)]; !ok {
 			c.Def()
 			c.Filter(fmt.Sprint(bg))
-			c.FeFlood(svg.Filterspec{Result: "bg"}, color.GetColor(bg), 1.0)
+			c.FeFlood(svg.Filterspec{Result: "bg"}, c.mapColor(color.GetColor(bg)), 1.0)
 			c.FeMerge([]string{`bg`, `SourceGraphic`})
 			c.Fend()
 			c.DefEnd()
@@ -224,6 +1307,19 @@ func (c *Canvas) addBG(bg vt10x.Color) {
 	}
 }
 
+// mapColor returns the replacement of hex from ColorMap or the theme, or hex itself.
+func (c *Canvas) mapColor(hex string) string {
+	if to, ok := c.opts.ColorMap[strings.ToLower(hex)]; ok {
+		return to
+	}
+
+	if to, ok := c.opts.Theme.Colors[strings.ToLower(hex)]; ok {
+		return to
+	}
+
+	return hex
+}
+
 func (c *Canvas) applyBG(bg vt10x.Color) string {
 	if bg != vt10x.DefaultBG {
 		if _, ok := c.colors[fmt.Sprint(bg)]; ok {
@@ -234,17 +1330,63 @@ func (c *Canvas) applyBG(bg vt10x.Color) string {
 	return ""
 }
 
-func generateKeyframes(cast asciicast.Cast, width int32) string {
+func generateKeyframes(cast asciicast.Cast, width int32, loop bool) string {
 	css := "@keyframes k {"
 	for i, frame := range cast.Events {
 		css += generateKeyframe(float32(frame.Time*100/cast.Header.Duration), width*int32(i))
 	}
 
+	if hold := loopHold(cast); loop && hold > 0 {
+		css += generateKeyframe(float32((cast.Header.Duration-hold)*100/cast.Header.Duration), 0)
+	}
+
 	css += "}"
 
 	return css
 }
 
+// generateFadeKeyframes returns one animation per frame, showing it from its event until the next one.
+func generateFadeKeyframes(cast asciicast.Cast, loop bool) string {
+	css := ""
+
+	for i := range cast.Events {
+		css += fmt.Sprintf("@keyframes o%d {", i)
+
+		if i == 0 {
+			css += "0%{opacity:1}"
+		} else {
+			css += fmt.Sprintf("0%%{opacity:0}%.3f%%{opacity:1}", cast.Events[i].Time*100/cast.Header.Duration)
+		}
+
+		if i < len(cast.Events)-1 {
+			css += fmt.Sprintf("%.3f%%{opacity:0}", cast.Events[i+1].Time*100/cast.Header.Duration)
+		}
+
+		if hold := loopHold(cast); loop && hold > 0 {
+			switch i {
+			case 0:
+				css += fmt.Sprintf("%.3f%%{opacity:1}", (cast.Header.Duration-hold)*100/cast.Header.Duration)
+			case len(cast.Events) - 1:
+				css += fmt.Sprintf("%.3f%%{opacity:0}", (cast.Header.Duration-hold)*100/cast.Header.Duration)
+			}
+		}
+
+		css += "}"
+	}
+
+	return css
+}
+
+// loopHold returns how long the first frame shows after its event, which SeamlessLoop
+// shows again at the end. Single frames have nothing to loop back from.
+func loopHold(cast asciicast.Cast) float64 {
+	if len(cast.Events) < 2 { //nolint:gomnd
+		return 0
+	}
+
+	return cast.Events[1].Time - cast.Events[0].Time
+}
+
 func generateKeyframe(percent float32, translate int32) string {
 	return fmt.Sprintf("%.3f%%{transform:translateX(-%dpx)}", percent, translate)
 }
@@ -2,60 +2,1097 @@ package svg_test
 
 import (
 	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mrmarble/termsvg/internal/svg"
 	"github.com/mrmarble/termsvg/internal/testutils"
 	"github.com/mrmarble/termsvg/pkg/asciicast"
+	"github.com/mrmarble/termsvg/pkg/color"
 	"github.com/sebdah/goldie/v2"
 )
 
 func TestExport(t *testing.T) {
-	input := testutils.GoldenData(t, "TestExportInput")
+	tests := map[string]struct {
+		cast   *asciicast.Cast
+		opts   svg.Options
+		golden string
+	}{
+		"Window":    {goldenCast(t), svg.Options{}, "TestExportOutput"},
+		"No window": {goldenCast(t), svg.Options{NoWindow: true}, "TestExportOutputNoWindow"},
+		// The clock keeps clear of the window controls
+		"Windows clock": {goldenCast(t), svg.Options{Window: svg.WindowWindows, ShowClock: true}, "TestClockWindowsOutput"},
+		"Attribute runs": {
+			newCast(12, 1, "\u001b[1mab\u001b[0mcd\u001b[41mef\u001b[0mgh\u001b[4;1mij\u001b[0mkl"), svg.Options{},
+			"TestAttributeRunsOutput",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := goldie.New(t)
+			g.Assert(t, tc.golden, []byte(render(t, tc.cast, tc.opts)))
+		})
+	}
+}
+
+func TestCommandTitle(t *testing.T) {
+	cast := goldenCast(t)
+	cast.Header.Command = "htop"
+
+	assertSvg(t, render(t, cast, svg.Options{}), []string{"<title>htop</title>"}, nil)
+}
+
+func TestThemes(t *testing.T) {
+	prompt := newCast(10, 2, "$")
+	windowTheme := svg.Theme{Background: "#000000", Window: "#333333"}
 
-	cast, err := asciicast.Unmarshal(input)
+	tests := map[string]svgTest{
+		"Dark":  {goldenCast(t), svg.Options{Theme: svg.DarkTheme}, []string{"fill:#282d35"}, nil},
+		"Light": {goldenCast(t), svg.Options{Theme: svg.LightTheme}, []string{"fill:#fafafa"}, nil},
+		// Default text takes the theme color, SGR 37 keeps palette 7
+		"Foreground": {
+			newCast(10, 2, "a\x1b[37mb"), svg.Options{Theme: svg.Theme{Foreground: "#ffffff"}},
+			[]string{".a{fill:#ffffff}", ".b{fill:#e5e5e5}"}, nil,
+		},
+		// The terminal keeps its background under a title bar of another color
+		"MacOS window color": {
+			prompt, svg.Options{Theme: windowTheme, Window: svg.WindowMacOS},
+			[]string{`style="fill:#333333"`, `style="fill:#000000"`}, nil,
+		},
+		"Windows window color": {
+			prompt, svg.Options{Theme: windowTheme, Window: svg.WindowWindows},
+			[]string{`style="fill:#333333"`, `style="fill:#000000"`}, nil,
+		},
+		// The run paints no background of its own, it shows the terminal painted below the title bar
+		"Default background runs": {
+			newCast(10, 2, "ab"), svg.Options{Theme: windowTheme, Window: svg.WindowWindows},
+			[]string{
+				`<rect x="0" y="0" width="160" height="110" style="fill:#000000" />`,
+				`<rect x="0" y="0" width="160" height="40" style="fill:#333333" />`,
+				`class="a"  >ab</text>`,
+			},
+			[]string{"<filter"},
+		},
+	}
+
+	runSvgTests(t, tests)
+}
+
+func TestLightThemePalette(t *testing.T) {
+	cast := newCast(10, 2, "\x1b[37mw\x1b[97mb\x1b[33my\x1b[93mY")
+
+	swatches, err := svg.Palette(*cast, svg.Options{Theme: svg.LightTheme})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	var output bytes.Buffer
+	// The blank cells bring in the default text color
+	if len(swatches) != 5 {
+		t.Fatalf("expected 5 colors, got %v", swatches)
+	}
+
+	// Readable as large text at least, which the terminal font size is close to
+	for _, swatch := range swatches {
+		if readable := color.EnsureContrast(swatch.Hex, svg.LightTheme.Background, 3); readable != swatch.Hex {
+			t.Fatalf("%s barely shows on %s", swatch.Hex, svg.LightTheme.Background)
+		}
+	}
+
+	// The color map goes first
+	mapped := svg.Options{Theme: svg.LightTheme, ColorMap: map[string]string{"#ffffff": "#000000"}}
+
+	swatches, err = svg.Palette(*cast, mapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hexes := []string{}
+	for _, swatch := range swatches {
+		hexes = append(hexes, swatch.Hex)
+	}
+
+	testutils.Diff(t, hexes, []string{"#696c77", "#000000", "#986801", "#c18401", "#383a42"})
+}
+
+func TestWindow(t *testing.T) {
+	hello := newCast(10, 2, "hello")
+
+	tests := map[string]svgTest{
+		"MacOS": {goldenCast(t), svg.Options{Window: svg.WindowMacOS}, []string{
+			`<circle cx="20" cy="20" r="7" style="fill:#ff5f58" />`,
+			`<circle cx="43" cy="20" r="7" style="fill:#ffbd2e" />`,
+			`<circle cx="66" cy="20" r="7" style="fill:#18c132" />`,
+		}, []string{"stroke:"}},
+		"Minimal": {goldenCast(t), svg.Options{Window: svg.WindowMinimal}, []string{"stroke:"}, []string{"<circle"}},
+		"No window": {
+			goldenCast(t), svg.Options{NoWindow: true, Window: svg.WindowMinimal}, nil, []string{"<circle", "stroke:"},
+		},
+		"Windows": {
+			hello, svg.Options{Window: svg.WindowWindows},
+			[]string{
+				`<rect x="0" y="0" width="160" height="110" style="fill:#282d35" />`,
+				`<line x1="55" y1="20" x2="65" y2="20" style="fill:none;stroke-width:1;stroke:#e5e5e5" />`,
+				`<rect x="95" y="15" width="10" height="10" style="fill:none;stroke-width:1;stroke:#e5e5e5" />`,
+				`<line x1="135" y1="15" x2="145" y2="25" style="fill:none;stroke-width:1;stroke:#e5e5e5" />`,
+				`transform="translate(20,60)"`,
+			},
+			[]string{"<circle"},
+		},
+		"Label centered": {newCast(80, 2, "$"), svg.Options{Label: "user@host:~"}, []string{
+			`<text x="500" y="20" text-anchor="middle"`, `>user@host:~</text>`,
+		}, nil},
+		"Label truncated": {newCast(20, 2, "$"), svg.Options{Label: "user@host:/very/long/path"}, []string{
+			`>user@ho…</text>`,
+		}, nil},
+	}
+
+	runSvgTests(t, tests)
+}
+
+func TestLayout(t *testing.T) {
+	hello := newCast(10, 2, "hello")
+	padding := &svg.Padding{Top: 10, Right: 5, Bottom: 40, Left: 30}
+
+	tests := map[string]svgTest{
+		"Default cell size": {newCast(10, 2, "a b\r\nc"), svg.Options{}, []string{
+			`width="160" height="110"`, `<text x="24" y="0"`, `<text x="0" y="25"`,
+		}, nil},
+		"Custom cell size": {newCast(10, 2, "a b\r\nc"), svg.Options{ColWidth: 10, RowHeight: 20}, []string{
+			`width="140" height="100"`, `<text x="20" y="0"`, `<text x="0" y="20"`,
+		}, nil},
+		"Default padding": {hello, svg.Options{}, []string{`width="160" height="110"`, `translate(20,60)`}, nil},
+		"Default plain padding": {hello, svg.Options{NoWindow: true}, []string{
+			`width="160" height="110"`, `translate(20,30)`,
+		}, nil},
+		"Padding": {hello, svg.Options{Padding: padding}, []string{`width="155" height="140"`, `translate(30,50)`}, nil},
+		"Plain padding": {hello, svg.Options{NoWindow: true, Padding: padding}, []string{
+			`width="155" height="100"`, `translate(30,10)`,
+		}, nil},
+		"Caption": {hello, svg.Options{Caption: "Say hello"}, []string{
+			`height="135"`,
+			`<text x="0" y="50" style="fill:#e5e5e5;font-family:monospace;font-size:20px" >Say hello</text>`,
+		}, nil},
+		"Two line caption": {hello, svg.Options{Caption: "Say\nhello"}, []string{
+			`height="160"`,
+			`<text x="0" y="50" style="fill:#e5e5e5;font-family:monospace;font-size:20px" >Say</text>`,
+			`<text x="0" y="75" style="fill:#e5e5e5;font-family:monospace;font-size:20px" >hello</text>`,
+		}, nil},
+		"Natural width": {newCast(80, 24, "hello"), svg.Options{}, []string{`<svg width="1000" height="660"`}, nil},
+		"Scaled width":  {newCast(80, 24, "hello"), svg.Options{Width: 800}, []string{`<svg width="800" height="528"`}, nil},
+	}
+
+	runSvgTests(t, tests)
+}
+
+func TestTrimBlankRows(t *testing.T) {
+	// Row 5 only shows up for a single frame and must not be clipped
+	cast := atTimes(newCast(80, 24, "1\r\n2\r\n3\r\n", "4\r\n5\u001b[1A\u001b[2K", "$"), 1, 1.5, 2)
+
+	tests := map[string]struct {
+		trim   bool
+		output string
+	}{
+		"Disabled": {false, `height="660"`},
+		"Enabled":  {true, `height="185"`},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assertSvg(t, render(t, cast, svg.Options{TrimBlankRows: tc.trim}), []string{tc.output}, nil)
+		})
+	}
+}
+
+func TestScrollRegion(t *testing.T) {
+	// Status line on the last row, scroll region on the first four
+	data := []string{"\u001b[5;1HSTATUS\u001b[1;4r\u001b[1;1H"}
+	times := []float64{0}
+
+	for i := 1; i <= 8; i++ {
+		data = append(data, fmt.Sprintf("line%d\r\n", i))
+		times = append(times, float64(i))
+	}
+
+	cast := atTimes(newCast(10, 5, data...), times...)
+	output := render(t, cast, svg.Options{})
+
+	testutils.Diff(t, strings.Count(output, ">STATUS</text>"), len(cast.Events))
+	// line1 scrolls out of the region once the fourth line is printed
+	testutils.Diff(t, strings.Count(output, ">line1</text>"), 3)
+}
+
+func TestNormalizeUnicode(t *testing.T) {
+	nfc, nfd := newCast(10, 1, "caf\u00e9"), newCast(10, 1, "cafe\u0301")
+	normalize := svg.Options{NormalizeUnicode: true}
+
+	testutils.Diff(t, render(t, nfd, normalize), render(t, nfc, normalize))
+
+	if render(t, nfd, svg.Options{}) == render(t, nfc, svg.Options{}) {
+		t.Fatal("decomposed text should render differently without normalization")
+	}
+}
+
+func TestFrameReuse(t *testing.T) {
+	// Cursor moves don't change what is drawn
+	moves := newCast(10, 1, "a", "\u001b[D", "\u001b[C", "b")
+	stacked := atTimes(newCast(10, 1, "a", "\u001b[D", "b"), 1, 2, 4)
+
+	// Room for the last new line without scrolling
+	lines := newCast(10, 11, "line0\r\n", "line1\r\n", "line2\r\n", "line3\r\n", "line4\r\n",
+		"line5\r\n", "line6\r\n", "line7\r\n", "line8\r\n", "line9\r\n")
+
+	// Frames are stacked, the svg only needs room for one
+	fades := []string{
+		`<svg width="160" height="85"`,
+		"@keyframes o0 {0%{opacity:1}50.000%{opacity:0}}",
+		"@keyframes o1 {0%{opacity:0}50.000%{opacity:1}100.000%{opacity:0}}",
+		"@keyframes o2 {0%{opacity:0}100.000%{opacity:1}}",
+	}
+
+	tests := map[string]struct {
+		cast         *asciicast.Cast
+		opts         svg.Options
+		texts, uses  int
+		want, absent []string
+	}{
+		"Plain":                {moves, svg.Options{}, 4, 0, nil, nil},
+		"Dedup frames":         {moves, svg.Options{DedupFrames: true}, 2, 2, nil, nil},
+		"Opacity":              {stacked, svg.Options{Layout: svg.LayoutOpacity}, 3, 0, fades, []string{"translateX"}},
+		"Opacity dedup frames": {stacked, svg.Options{Layout: svg.LayoutOpacity, DedupFrames: true}, 2, 1, fades, nil},
+		"Lines":                {lines, svg.Options{}, 55, 0, nil, nil},
+		"Delta frames":         {lines, svg.Options{DeltaFrames: true}, 10, 55, nil, nil},
+		"Opacity delta frames": {lines, svg.Options{DeltaFrames: true, Layout: svg.LayoutOpacity}, 10, 55, nil, nil},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			output, stats := renderStats(t, tc.cast, tc.opts)
+
+			testutils.Diff(t, strings.Count(output, "<text"), tc.texts)
+			testutils.Diff(t, strings.Count(output, "<use"), tc.uses)
+			assertSvg(t, output, tc.want, tc.absent)
+
+			if tc.opts.DedupFrames {
+				testutils.Diff(t, stats, svg.Stats{Frames: len(tc.cast.Events), Duplicates: tc.uses})
+			}
+		})
+	}
+}
+
+func TestLongRecordingIsStacked(t *testing.T) {
+	tests := map[string]struct {
+		frames  int
+		stacked bool
+	}{
+		"Short": {1000, false}, // 1000 frames 1000px wide
+		"Long":  {1001, true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cast := newCast(80, 1, strings.Split(strings.Repeat("x", tc.frames), "")...)
+
+			testutils.Diff(t, strings.Contains(render(t, cast, svg.Options{}), "@keyframes o0 "), tc.stacked)
+		})
+	}
+}
+
+func TestTiming(t *testing.T) {
+	// The recording goes on after its last event
+	loop := newCast(10, 1, "a", "b")
+	loop.Header.Duration = 4
+
+	long := newCast(10, 2, "a", "b")
+	long.Header.Duration = 10
+
+	short := atTimes(newCast(10, 1, "a", "b"), 0.1, 0.3)
+
+	runTimingTests(t, map[string]timingTest{
+		// The event going back is merged with the one before it
+		"Backward times": {atTimes(newCast(10, 1, "a", "b", "c", "d"), 1, 3, 2, 4), svg.Options{}, 0, []string{
+			"@keyframes k {25.000%{transform:translateX(-0px)}75.000%{transform:translateX(-160px)}" +
+				"100.000%{transform:translateX(-320px)}}",
+		}},
+		// The first frame shows for 2s, 1s of them after its event, held again from 4s to 5s
+		"Seamless loop": {loop, svg.Options{SeamlessLoop: true}, 0, []string{
+			"animation-duration:5.00s",
+			"@keyframes k {20.000%{transform:translateX(-0px)}40.000%{transform:translateX(-160px)}" +
+				"80.000%{transform:translateX(-0px)}}",
+		}},
+		"Seamless loop opacity": {loop, svg.Options{SeamlessLoop: true, Layout: svg.LayoutOpacity}, 0, []string{
+			"@keyframes o0 {0%{opacity:1}40.000%{opacity:0}80.000%{opacity:1}}",
+			"@keyframes o1 {0%{opacity:0}40.000%{opacity:1}80.000%{opacity:0}}",
+		}},
+		"Without loop": {loop, svg.Options{}, 0, []string{
+			"animation-duration:4.00s",
+			"@keyframes k {25.000%{transform:translateX(-0px)}50.000%{transform:translateX(-160px)}}",
+		}},
+		"Duration": {short, svg.Options{}, 0, []string{
+			"animation-duration:0.30s", "33.333%{transform:translateX(-0px)}100.000%{transform:translateX(-160px)}",
+		}},
+		// The last frame is held for the rest of the second
+		"Min duration": {short, svg.Options{MinDuration: 1}, 0, []string{
+			"animation-duration:1.00s", "10.000%{transform:translateX(-0px)}30.000%{transform:translateX(-160px)}}",
+		}},
+		"Shorter min duration": {short, svg.Options{MinDuration: 0.2}, 0, []string{"animation-duration:0.30s"}},
+		"Recorded speed": {long, svg.Options{}, 0, []string{
+			"animation-duration:10.00s", "10.000%{transform:translateX(-0px)}20.000%",
+		}},
+		"Same speed": {long, svg.Options{Speed: 1}, 0, []string{
+			"animation-duration:10.00s", "10.000%{transform:translateX(-0px)}20.000%",
+		}},
+		"Slower": {long, svg.Options{Speed: 0.25}, 0, []string{
+			"animation-duration:40.00s", "10.000%{transform:translateX(-0px)}20.000%",
+		}},
+		"Faster": {long, svg.Options{Speed: 2}, 0, []string{
+			"animation-duration:5.00s", "10.000%{transform:translateX(-0px)}20.000%",
+		}},
+	})
+}
+
+func TestFrameTimes(t *testing.T) {
+	bursts := atTimes(newCast(10, 1, "a", "b", "c", "d"), 1, 1.0001, 1.0002, 5)
+	idle := atTimes(newCast(10, 1, "a", "\u001b[?25l", "\ra", "b"), 1, 2, 5, 10)
+	stepped := atTimes(newCast(10, 1, "a", "b", "c"), 1, 2, 4)
+
+	const idleDropped = "10.000%{transform:translateX(-0px)}100.000%{transform:translateX(-160px)}"
+
+	runTimingTests(t, map[string]timingTest{
+		"Dwell": {bursts, svg.Options{}, 0, []string{
+			"20.000%{transform:translateX(-0px)}20.002%{transform:translateX(-160px)}" +
+				"20.004%{transform:translateX(-320px)}100.000%{transform:translateX(-480px)}",
+		}},
+		"Min dwell": {bursts, svg.Options{MinDwell: 0.5}, 0, []string{
+			"16.667%{transform:translateX(-0px)}25.001%{transform:translateX(-160px)}" +
+				"33.334%{transform:translateX(-320px)}100.000%{transform:translateX(-480px)}",
+		}},
+		"Min and max dwell": {bursts, svg.Options{MinDwell: 0.5, MaxDwell: 1}, 0, []string{
+			"33.333%{transform:translateX(-0px)}50.000%{transform:translateX(-160px)}" +
+				"66.667%{transform:translateX(-320px)}100.000%{transform:translateX(-480px)}",
+		}},
+		// Hiding the cursor and rewriting the same text leave the screen as it was, the last frame shows both
+		"Drop idle frames": {idle, svg.Options{DropIdleFrames: true}, 2, []string{idleDropped, ">ab</text>"}},
+		"Keep idle frames": {idle, svg.Options{}, 4, []string{
+			"10.000%{transform:translateX(-0px)}20.000%{transform:translateX(-160px)}", ">ab</text>",
+		}},
+		"Drop and dedup idle frames": {
+			idle, svg.Options{DropIdleFrames: true, DedupFrames: true}, 2, []string{idleDropped, ">ab</text>"},
+		},
+		// Out of time right away, the first frame is still drawn and shows for the whole animation
+		"Deadline passed": {stepped, svg.Options{Deadline: time.Now()}, 1, []string{
+			"@keyframes k {25.000%{transform:translateX(-0px)}}",
+		}},
+		"Deadline passed opacity": {stepped, svg.Options{Deadline: time.Now(), Layout: svg.LayoutOpacity}, 1, []string{
+			"@keyframes o0 {0%{opacity:1}}",
+		}},
+		"In time": {stepped, svg.Options{Deadline: time.Now().Add(time.Hour)}, 3, []string{
+			"@keyframes k {25.000%{transform:translateX(-0px)}" +
+				"50.000%{transform:translateX(-160px)}100.000%{transform:translateX(-320px)}}",
+		}},
+	})
+}
+
+func TestStartPaused(t *testing.T) {
+	for name, paused := range map[string]bool{"Disabled": false, "Enabled": true} {
+		t.Run(name, func(t *testing.T) {
+			output := render(t, goldenCast(t), svg.Options{StartPaused: paused})
+
+			testutils.Diff(t, strings.Contains(output, "animation-play-state:paused"), paused)
+			testutils.Diff(t, strings.Contains(output, "svg:hover g{animation-play-state:running!important}"), paused)
+		})
+	}
+}
+
+func TestEscapes(t *testing.T) {
+	runSvgTests(t, map[string]svgTest{
+		"G0 line drawing": {newCast(4, 1, "\u001b(0qq\u001b(Bqq"), svg.Options{}, []string{">──qq</text>"}, nil},
+		"Shift out to G1": {newCast(4, 1, "\u001b)0\u000eqq\u000fqq"), svg.Options{}, []string{">──qq</text>"}, nil},
+		"G1 not selected": {newCast(4, 1, "\u001b)0qqqq"), svg.Options{}, []string{">qqqq</text>"}, nil},
+		"Split in events": {
+			newCast(4, 1, "\u001b)0\u000eqq", "\u000fqq"), svg.Options{}, []string{">──qq</text>"}, nil,
+		},
+
+		"Colon truecolor": {
+			newCast(4, 1, "\u001b[38:2::255:0:0mred"), svg.Options{}, []string{".a{fill:#ff0000}"}, nil,
+		},
+		"Colon w/o color space": {
+			newCast(4, 1, "\u001b[38:2:255:0:0mred"), svg.Options{}, []string{".a{fill:#ff0000}"}, nil,
+		},
+		"Colon 256 colors": {newCast(4, 1, "\u001b[38:5:196mred"), svg.Options{}, []string{".a{fill:#ff0000}"}, nil},
+		"Mixed separators": {
+			newCast(4, 1, "\u001b[1;38:2::255:0:0;4:3mred"), svg.Options{}, []string{".a{fill:#ff0000}"}, nil,
+		},
+		"Underline color": {
+			newCast(4, 1, "\u001b[31m\u001b[58:2::0:255:0mred"), svg.Options{}, []string{".a{fill:#cd0000}"}, nil,
+		},
+		"Extended colors": {newCast(2, 1, "\u001b[38;5;208mx\u001b[38;2;10;20;30my"), svg.Options{}, []string{
+			".a{fill:#ff8700}", ".b{fill:#0a141e}",
+		}, nil},
+
+		// Frames before the change keep the old color, after it the whole screen is
+		// repainted with the new one as terminals do
+		"Palette long spec": {newCast(4, 1, "\u001b[31ma", "\u001b]4;1;rgb:00/80/ff\u0007b"), svg.Options{}, []string{
+			".a{fill:#cd0000}", `class="a"  >a</text>`, "{fill:#0080ff}",
+		}, nil},
+		"Palette short spec": {newCast(4, 1, "\u001b[31ma", "\u001b]4;1;rgb:f/0/8\u001b\\b"), svg.Options{}, []string{
+			".a{fill:#cd0000}", `class="a"  >a</text>`, "{fill:#ff0088}",
+		}, nil},
+		"Palette wide spec": {newCast(4, 1, "\u001b[31ma", "\u001b]4;1;rgb:ffff/0000/8080\u0007b"), svg.Options{}, []string{
+			".a{fill:#cd0000}", `class="a"  >a</text>`, "{fill:#ff0080}",
+		}, nil},
+		"Palette reset": {
+			newCast(4, 1, "\u001b[31ma", "\u001b]4;1;rgb:00/80/ff\u0007\u001b]104;1\u0007b"), svg.Options{},
+			[]string{".a{fill:#cd0000}", `class="a"  >a</text>`}, nil,
+		},
+		"Palette other index": {newCast(4, 1, "\u001b[31ma", "\u001b]4;2;rgb:00/80/ff\u0007b"), svg.Options{}, []string{
+			".a{fill:#cd0000}", `class="a"  >a</text>`,
+		}, nil},
+
+		// Saved in one frame and restored in a later one
+		"SCOSC/SCORC": {newCast(10, 2, "ab\u001b[s\u001b[2;5Hcd", "\u001b[uX"), svg.Options{}, []string{
+			`x="0" y="0" class="a"  >abX</text>`,
+		}, nil},
+		"DECSC/DECRC": {newCast(10, 2, "ab\u001b7\u001b[2;5Hcd", "\u001b8X"), svg.Options{}, []string{
+			`x="0" y="0" class="a"  >abX</text>`,
+		}, nil},
+	})
+}
+
+func TestColumns(t *testing.T) {
+	gridAlign := svg.Options{GridAlign: true}
+	smallCells := svg.Options{GridAlign: true, ColWidth: 10, RowHeight: 20}
+
+	tests := map[string]struct {
+		cast         *asciicast.Cast
+		opts         svg.Options
+		want, absent []string
+		texts        int // Not checked when 0
+	}{
+		// The character keeps its cell, what follows stays in its column
+		"C1 control": {newCast(4, 1, "a\u0085bc"), svg.Options{}, []string{
+			`x="0" y="0" class="a"  >a</text>`, `x="24" y="0" class="a" >bc</text>`,
+		}, nil, 2},
+		"Zero width space": {newCast(4, 1, "a\u200bbc"), svg.Options{}, []string{
+			`x="0" y="0" class="a"  >a</text>`, `x="24" y="0" class="a" >bc</text>`,
+		}, nil, 2},
+		"Control in charset": {newCast(4, 1, "\u001b(0\u0001\u001b(Bc"), svg.Options{}, []string{
+			`x="12" y="0" class="a"  >c</text>`,
+		}, nil, 1},
+
+		// The lone glyph is placed by its x already
+		"Grid align": {newCast(8, 1, "abcd e"), gridAlign, []string{
+			`x="0" y="0" class="a" textLength="48" lengthAdjust="spacing"  >abcd</text>`,
+		}, []string{`textLength="12"`}, 0},
+		"Grid align cell size": {
+			newCast(8, 1, "abcd e"), smallCells,
+			[]string{`textLength="40" lengthAdjust="spacing"`}, []string{`textLength="10"`}, 0,
+		},
+		"Grid align off": {
+			newCast(8, 1, "abcd e"), svg.Options{}, []string{`x="0" y="0" class="a"  >abcd</text>`}, []string{"textLength"}, 0,
+		},
+		// Each run starts on its own column, wide glyphs don't push what follows
+		"Wide glyphs": {newCast(8, 1, "ab中cd宽"), gridAlign, []string{
+			`x="0" y="0"`, `x="24" y="0"`, `x="36" y="0"`, `x="60" y="0"`,
+		}, nil, 4},
+		"Wide glyphs cell size": {newCast(8, 1, "ab中cd宽"), smallCells, []string{
+			`x="0" y="0"`, `x="20" y="0"`, `x="30" y="0"`, `x="50" y="0"`,
+		}, nil, 4},
+		"Wide glyphs off": {newCast(8, 1, "ab中cd宽"), svg.Options{}, []string{`x="0" y="0"`}, nil, 1},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			output := render(t, tc.cast, tc.opts)
+			assertSvg(t, output, tc.want, tc.absent)
+
+			if tc.texts > 0 {
+				testutils.Diff(t, strings.Count(output, "<text"), tc.texts)
+			}
+		})
+	}
+}
+
+func TestRuns(t *testing.T) {
+	flat := svg.Options{FlatColor: true}
+	overstrike := svg.Options{Overstrike: true}
+	colorMap := svg.Options{ColorMap: map[string]string{"#cd0000": "#00ff00", "#00cd00": "#123456"}}
+
+	runSvgTests(t, map[string]svgTest{
+		// Trailing spaces only draw something over a background
+		"Trailing spaces": {newCast(10, 2, "ab\u00a0\u00a0\r\n\x1b[41mcd\u00a0\x1b[0m"), svg.Options{}, []string{
+			">ab</text>", ">cd\u00a0</text>",
+		}, nil},
+
+		"Runs apart":  {newCast(10, 1, "ab cd"), svg.Options{}, []string{`>ab</text>`, `>cd</text>`}, nil},
+		"Runs merged": {newCast(10, 1, "ab cd"), svg.Options{MergeGap: 1}, []string{`>ab cd</text>`}, nil},
+		"Spaces kept": {
+			newCast(10, 1, "ab  cd"), svg.Options{MergeGap: 2}, []string{`xml:space="preserve"  >ab  cd</text>`}, nil,
+		},
+		"Runs too far": {newCast(10, 1, "ab  cd"), svg.Options{MergeGap: 1}, []string{`>ab</text>`, `>cd</text>`}, nil},
+		"Runs of styles": {
+			newCast(10, 1, "ab \x1b[31mcd"), svg.Options{MergeGap: 1}, []string{`>ab</text>`, `>cd</text>`}, nil,
+		},
+
+		"Overstrike bold": {
+			newCast(1, 1, "a\ba"), overstrike, []string{`class="a" font-weight="bold" >a</text>`}, nil,
+		},
+		"Overstrike underline": {
+			newCast(1, 1, "_\bb"), overstrike, []string{`class="a" text-decoration="underline" >b</text>`}, nil,
+		},
+		"Overstrike disabled": {newCast(1, 1, "a\ba"), svg.Options{}, []string{`class="a" >a</text>`}, nil},
+		"Overwrite":           {newCast(1, 1, "a\bb"), overstrike, []string{`class="a" >b</text>`}, nil},
+
+		// Without classes the color is set once, on the group of the frames
+		"Flat color": {newCast(10, 1, "ab cd"), flat, []string{";fill:#e5e5e5;"}, []string{"class=", "{fill:", "<filter"}},
+		"Flat color colored": {
+			newCast(10, 1, "ab \x1b[31mcd"), flat, []string{"class=", "{fill:"}, []string{";fill:#e5e5e5;", "<filter"},
+		},
+		"Flat color background": {
+			newCast(10, 1, "ab \x1b[41mcd"), flat, []string{"class=", "{fill:", "<filter"}, []string{";fill:#e5e5e5;"},
+		},
+
+		// Colors not in the map, like the default text, are left alone
+		"Color map": {newCast(4, 1, "\u001b[31mx\u001b[0;42my\u001b[0mz"), colorMap, []string{
+			".a{fill:#00ff00}", ".b{fill:#e5e5e5}", `flood-color="#123456"`,
+		}, []string{"#cd0000", "#00cd00"}},
+	})
+}
+
+func TestLinkify(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		output []string
+	}{
+		"Bare url": {"see https://example.com/a?b=1&c=2.", []string{
+			`class="a"  >see</text>`,
+			`<a xlink:href="https://example.com/a?b=1&amp;c=2" xlink:title="https://example.com/a?b=1&amp;c=2">` + "\n" +
+				`<text x="48" y="0" class="a"  >https://example.com/a?b=1&amp;c=2</text>` + "\n</a>",
+			`<text x="396" y="0" class="a"  >.</text>`,
+		}},
+		"Colored": {"(\u001b[34mhttp://x.io\u001b[0m)", []string{
+			`<text x="0" y="0" class="a"  >(</text>`,
+			`<a xlink:href="http://x.io" xlink:title="http://x.io">` + "\n" +
+				`<text x="12" y="0" class="b"  >http://x.io</text>`,
+			`<text x="144" y="0" class="a"  >)</text>`,
+		}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			output := render(t, newCast(40, 1, tc.input), svg.Options{Linkify: true})
+			assertSvg(t, output, tc.output, nil)
+
+			testutils.Diff(t, strings.Count(output, "<a "), 1)
+		})
+	}
+}
+
+func TestMinContrast(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		contrast float64
+		output   []svg.Swatch
+	}{
+		"Disabled": {"\u001b[30mx\u001b[0my", 0, []svg.Swatch{{Hex: "#000000", Class: "a"}, {Hex: "#e5e5e5", Class: "b"}}},
+		"Enabled":  {"\u001b[30mx\u001b[0my", 4.5, []svg.Swatch{{Hex: "#939393", Class: "a"}, {Hex: "#e5e5e5", Class: "b"}}},
+		// Against the background of the text, the default text after it keeps its color
+		"Colored background": {
+			"\u001b[33;43mx", 4.5,
+			[]svg.Swatch{{Hex: "#565600", Class: "a"}, {Hex: "#cdcd00", Class: "b"}, {Hex: "#e5e5e5", Class: "c"}},
+		},
+		"Inverse": {
+			"\u001b[7;33mx", 4.5,
+			[]svg.Swatch{{Hex: "#535353", Class: "a"}, {Hex: "#cdcd00", Class: "b"}, {Hex: "#e5e5e5", Class: "c"}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			palette, err := svg.Palette(*newCast(2, 1, tc.input), svg.Options{MinContrast: tc.contrast})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			testutils.Diff(t, palette, tc.output)
+		})
+	}
+}
+
+func TestScrollback(t *testing.T) {
+	lines := []string{"line0\r\n", "line1\r\n", "line2\r\n", "line3\r\n", "line4\r\n"}
+	// Lines longer than the terminal scroll once more per row they wrap onto
+	wrapped := []string{"START\r\n", strings.Repeat("x", 10) + strings.Repeat("y", 10) + "zz\r\n", "end\r\n"}
+
+	cleared := make([]string, 0, 6)
+	for i := 0; i < 5; i++ {
+		cleared = append(cleared, fmt.Sprintf("line %d\r\n", i))
+	}
+	cleared = append(cleared, "\x1b[3Jafter")
+
+	tests := map[string]struct {
+		events       []string
+		limit        int
+		rows         []string // Expected from the top row down
+		want, absent []string
+	}{
+		// Lines scrolled off the 2 rows terminal are kept, one per row
+		"Lines":   {lines, 0, []string{">line0<", ">line1<", ">line2<", ">line3<", ">line4<"}, nil, nil},
+		"Wrapped": {wrapped, 0, []string{">START<", ">xxxxxxxxxx<", ">yyyyyyyyyy<", ">zz<", ">end<"}, nil, nil},
+		// 2 rows and 1 of scrollback, the last one empty, keep the last 2 lines printed
+		"Limited": {lines, 1, []string{">line3<", ">line4<"}, nil, []string{">line0<", ">line1<", ">line2<"}},
+		// Fewer lines than the limit, but more rows once wrapped
+		"Limited wrapped": {wrapped, 4, []string{">START<", ">xxxxxxxxxx<", ">yyyyyyyyyy<", ">zz<", ">end<"}, nil, nil},
+		// The screen kept the last line printed and the empty one the cursor was on
+		"Cleared": {
+			cleared, 0, nil,
+			[]string{`x="60" y="0" class="a"  >4</text>`, `x="0" y="25" class="a"  >after</text>`},
+			[]string{">0</text>", ">1</text>", ">2</text>", ">3</text>"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := svg.Options{FullScrollback: true, ScrollbackLines: tc.limit}
+			output, stats := renderStats(t, newCast(10, 2, tc.events...), opts)
+
+			for i, row := range tc.rows {
+				s := regexp.MustCompile(fmt.Sprintf(`y="%d" class="a" +%s/text>`, i*25, row))
+				if !s.MatchString(output) {
+					t.Fatalf("%s not found in svg:\n%s", s, output)
+				}
+			}
+
+			assertSvg(t, output, tc.want, append(tc.absent, "@keyframes"))
+			testutils.Diff(t, stats.Frames, 1)
+		})
+	}
+}
+
+func TestShowClock(t *testing.T) {
+	cast := atTimes(newCast(10, 1, "a", "b", "\b"), 1.5, 3.9, 65)
+
+	tests := map[string]struct {
+		opts   svg.Options
+		clocks []string
+	}{
+		"Translate": {svg.Options{ShowClock: true}, []string{
+			`<text x="120" y="-40" text-anchor="end"`, `>0:01</text>`,
+			`<text x="280" y="-40" text-anchor="end"`, `>0:03</text>`,
+			`<text x="440" y="-40" text-anchor="end"`, `>1:05</text>`,
+		}},
+		"Opacity": {svg.Options{ShowClock: true, Layout: svg.LayoutOpacity}, []string{
+			`<text x="120" y="-40"`, `>0:01</text>`, `>0:03</text>`, `>1:05</text>`,
+		}},
+		"No window": {svg.Options{ShowClock: true, NoWindow: true}, []string{`<text x="120" y="-15"`, `>0:01</text>`}},
+		"Dedup": {
+			svg.Options{ShowClock: true, DedupFrames: true}, []string{`>0:01</text>`, `>0:03</text>`, `>1:05</text>`},
+		},
+	}
 
-	// Pass empty override bg and text colors
-	svg.Export(*cast, &output, "", "", false)
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assertSvg(t, render(t, cast, tc.opts), tc.clocks, nil)
+		})
+	}
+}
+
+func TestShowClockStatic(t *testing.T) {
+	cast := atTimes(newCast(10, 1, "a", "b"), 1.5, 3.9)
+
+	tests := map[string]func(output *bytes.Buffer) error{
+		"Poster": func(output *bytes.Buffer) error {
+			return svg.Poster(*cast, output, svg.Options{ShowClock: true}, -1)
+		},
+		"Full scrollback": func(output *bytes.Buffer) error {
+			_, err := svg.Export(*cast, output, svg.Options{ShowClock: true, FullScrollback: true})
+
+			return err
+		},
+	}
+
+	for name, draw := range tests {
+		t.Run(name, func(t *testing.T) {
+			var output bytes.Buffer
+
+			if err := draw(&output); err != nil {
+				t.Fatal(err)
+			}
+
+			assertSvg(t, output.String(), nil, []string{`text-anchor="end"`})
+		})
+	}
+
+	boards, err := svg.Storyboard(*cast, svg.Options{ShowClock: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, board := range boards {
+		assertSvg(t, string(board), nil, []string{`text-anchor="end"`})
+	}
+}
+
+func TestDeviceControlStrings(t *testing.T) {
+	tmux := "a\u001bPtmux;\u001b\u001b[31mX\u001b\\b"
+
+	tests := map[string]struct {
+		opts   svg.Options
+		events []string
+		output []string
+	}{
+		"Sixel": {svg.Options{}, []string{"a\u001bPq#0;2;0;0;0~-\u001b\\b"}, []string{`class="a"  >ab</text>`}},
+		"Split across events": {
+			svg.Options{}, []string{"a\u001bPq#0;2;0", ";0;0~-\u001b", "\\b"}, []string{`class="a"  >ab</text>`},
+		},
+		"Tmux dropped": {svg.Options{}, []string{tmux}, []string{`class="a"  >ab</text>`}},
+		"Cancelled": {
+			svg.Options{}, []string{"a\u001bPq#0\u0018b\u001bPq#0\u001ac"}, []string{`class="a"  >abc</text>`},
+		},
+		"Unterminated":        {svg.Options{}, []string{"a\u001bP1$r", "#0", "\u001b[mb"}, []string{`class="a"  >ab</text>`}},
+		"Unterminated at end": {svg.Options{}, []string{"a", "b\u001bPq#0"}, []string{`class="a"  >ab</text>`}},
+		"Tmux passthrough": {svg.Options{TmuxPassthrough: true}, []string{tmux}, []string{
+			`x="0" y="0" class="a"  >a</text>`, `x="12" y="0" class="b"  >Xb</text>`, ".b{fill:#cd0000}",
+		}},
+	}
 
-	g := goldie.New(t)
-	g.Assert(t, "TestExportOutput", output.Bytes())
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assertSvg(t, render(t, newCast(4, 1, tc.events...), tc.opts), tc.output, []string{">#0", ">tmux", ">~"})
+		})
+	}
 }
 
-func TestNoWindow(t *testing.T) {
-	input := testutils.GoldenData(t, "TestExportInput")
+func TestStoryboard(t *testing.T) {
+	cast := atTimes(newCast(4, 1, "a", "\u001b[?25l", "b"), 1, 2, 4)
+	cast.Header.Duration = 6
 
-	cast, err := asciicast.Unmarshal(input)
+	boards, err := svg.Storyboard(*cast, svg.Options{Caption: "demo"})
 	if err != nil {
 		t.Fatal(err)
 	}
 
+	// The second event leaves the screen as it was, the first board shows until the third
+	tests := []struct {
+		text  string
+		label string
+	}{
+		{`class="a"  >a</text>`, ">1/2, shown 3.00s</text>"},
+		{`class="a"  >ab</text>`, ">2/2, shown 2.00s</text>"},
+	}
+
+	testutils.Diff(t, len(boards), len(tests))
+
+	for i, tc := range tests {
+		assertSvg(t, string(boards[i]), []string{tc.text, tc.label, ">demo</text>"}, []string{"@keyframes"})
+	}
+}
+
+func TestAnchorBottom(t *testing.T) {
+	cast := newCast(4, 4, "a\r\n", "b\r\n", "c")
+
+	tests := map[string]struct {
+		opts   svg.Options
+		frames []string
+	}{
+		// The latest line is always on the bottom row, the older ones above it
+		"Rows": {svg.Options{AnchorBottom: true}, []string{
+			`<text x="0" y="75" class="a"  >a</text>`,
+			`<text x="0" y="50" class="a"  >a</text><text x="0" y="75" class="a"  >b</text>`,
+			`<text x="0" y="25" class="a"  >a</text><text x="0" y="50" class="a"  >b</text>` +
+				`<text x="0" y="75" class="a"  >c</text>`,
+		}},
+		"Shared rows": {svg.Options{AnchorBottom: true, DeltaFrames: true}, []string{
+			`<use x="0" y="75" xlink:href="#r0" />`,
+			`<use x="0" y="50" xlink:href="#r0" />`,
+			`<use x="0" y="25" xlink:href="#r0" />`,
+		}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assertSvg(t, strings.ReplaceAll(render(t, cast, tc.opts), "\n", ""), tc.frames, nil)
+		})
+	}
+}
+
+func TestPoster(t *testing.T) {
+	cast := newCast(4, 1, "a", "b", "\u001b[2J")
+
+	tests := map[string]struct {
+		at   float64
+		text string
+	}{
+		// The recording ends on a cleared screen, which makes a poor preview
+		"Last with content": {-1, `class="a"  >ab</text>`},
+		"At time":           {1.5, `class="a"  >a</text>`},
+		"Before the start":  {0.5, ""},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var output bytes.Buffer
+
+			if err := svg.Poster(*cast, &output, svg.Options{}, tc.at); err != nil {
+				t.Fatal(err)
+			}
+
+			absent := []string{"@keyframes"}
+			if tc.text == "" {
+				absent = append(absent, "<text")
+			}
+
+			assertSvg(t, output.String(), []string{tc.text}, absent)
+		})
+	}
+}
+
+func TestInputEvents(t *testing.T) {
+	cast := newCast(4, 1, "x", "ok")
+	// Typed keys show through the echoed output, not by themselves
+	cast.Events[0].EventType = asciicast.Input
+
+	output, stats := renderStats(t, cast, svg.Options{})
+
+	testutils.Diff(t, stats.Frames, 1)
+	assertSvg(t, output, []string{">ok</text>"}, nil)
+}
+
+func TestSideBySide(t *testing.T) {
+	left := newCast(10, 2, "before")
+	right := atTimes(newCast(20, 2, "after"), 3)
+
 	var output bytes.Buffer
 
-	// Pass empty override bg and text colors
-	svg.Export(*cast, &output, "", "", true)
+	if _, err := svg.SideBySide(*left, *right, &output, svg.Options{NoWindow: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 10 and 20 columns padded 20px on each side, plus 20px between them
+	assertSvg(t, output.String(), []string{`width="460"`}, nil)
+
+	images := regexp.MustCompile(`data:image/svg\+xml;base64,([^"]*)`).FindAllStringSubmatch(output.String(), -1)
+	if len(images) != 2 {
+		t.Fatalf("expected 2 embedded recordings, got %d", len(images))
+	}
+
+	for i, want := range []string{">before<", ">after<"} {
+		side, err := base64.StdEncoding.DecodeString(images[i][1])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The shorter recording is stretched to the longer one
+		assertSvg(t, string(side), []string{want, "animation-duration:3.00s"}, nil)
+	}
+}
+
+func TestFont(t *testing.T) {
+	cast := newCast(10, 2, "echo")
+
+	// Cells of another width than the fallback fonts' keep the glyphs on them
+	tests := map[string]struct {
+		font      string
+		output    []string
+		stretched bool
+	}{
+		"Default": {"", []string{`width="160"`, "font-family:Monaco,"}, false},
+		"Fira":    {"fira-code", []string{`width="160"`, "font-family:'Fira Code',Monaco,"}, false},
+		"Ubuntu":  {"ubuntu-mono", []string{`width="140"`, "font-family:'Ubuntu Mono',Monaco,", `textLength="40"`}, true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			output := render(t, cast, svg.Options{NoWindow: true, Font: tc.font})
+			assertSvg(t, output, tc.output, nil)
+
+			if stretched := strings.Contains(output, "textLength"); stretched != tc.stretched {
+				t.Fatalf("expected stretched %t, got svg:\n%s", tc.stretched, output)
+			}
+		})
+	}
+
+	_, err := svg.Export(*cast, new(bytes.Buffer), svg.Options{Font: "comic-sans"})
+	if !errors.Is(err, svg.ErrUnknownFont) {
+		t.Fatalf("expected ErrUnknownFont, got %v", err)
+	}
+}
+
+func TestZeroSize(t *testing.T) {
+	_, err := svg.Export(*newCast(0, 24, "$"), new(bytes.Buffer), svg.Options{})
+	if !errors.Is(err, svg.ErrSize) {
+		t.Fatalf("expected ErrSize, got %v", err)
+	}
+}
+
+func TestCallouts(t *testing.T) {
+	cast := newCast(10, 2, "x", "x", "x")
+	cast.Header.Duration = 4
+
+	callouts := []svg.Callout{{Time: 2, Duration: 1, Text: "note", Row: 1, Col: 2}}
+	output := render(t, cast, svg.Options{NoWindow: true, Callouts: callouts})
+
+	// Only on the second frame, 160px wide, from 2s to 3s
+	testutils.Diff(t, strings.Count(output, ">note</text>"), 1)
+	assertSvg(t, output, []string{`<text x="194" y="25"`}, nil)
+}
+
+func TestEmbedCastFailure(t *testing.T) {
+	cast := newCast(10, 2, "ab")
+	cast.Header.IdleTimeLimit = math.Inf(1) // Not representable in json
 
-	g := goldie.New(t)
-	g.Assert(t, "TestExportOutputNoWindow", output.Bytes())
+	if _, err := svg.Export(*cast, new(bytes.Buffer), svg.Options{EmbedCast: true}); err == nil {
+		t.Fatal("expected the cast to fail to embed")
+	}
 }
 
 func BenchmarkExport(b *testing.B) {
-	input := testutils.GoldenData(b, "TestExportInput")
+	cast := goldenCast(b)
 
-	cast, err := asciicast.Unmarshal(input)
-	if err != nil {
-		b.Fatal(err)
+	for i := 0; i < b.N; i++ {
+		if _, err := svg.Export(*cast, new(bytes.Buffer), svg.Options{}); err != nil {
+			b.Fatal(err)
+		}
 	}
+}
+
+func BenchmarkExportFlatColor(b *testing.B) {
+	cast := goldenCast(b)
 
 	for i := 0; i < b.N; i++ {
-		var output bytes.Buffer
+		if _, err := svg.Export(*cast, new(bytes.Buffer), svg.Options{FlatColor: true}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// goldenCast returns the recording the golden files are made from.
+func goldenCast(t testing.TB) *asciicast.Cast {
+	t.Helper()
+
+	cast, err := asciicast.Unmarshal(testutils.GoldenData(t, "TestExportInput"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cast
+}
+
+// newCast returns a recording of the given size with an output event per data, one second apart.
+func newCast(width, height int, data ...string) *asciicast.Cast {
+	cast := asciicast.New()
+	cast.Header.Width = width
+	cast.Header.Height = height
+
+	for i, d := range data {
+		cast.Events = append(cast.Events, asciicast.Event{Time: float64(i + 1), EventType: asciicast.Output, EventData: d})
+	}
+	cast.RecomputeDuration()
+
+	return cast
+}
+
+// atTimes moves the events of cast to the given times, in order.
+func atTimes(cast *asciicast.Cast, times ...float64) *asciicast.Cast {
+	for i, t := range times {
+		cast.Events[i].Time = t
+	}
+	cast.RecomputeDuration()
+
+	return cast
+}
+
+func render(t *testing.T, cast *asciicast.Cast, opts svg.Options) string {
+	t.Helper()
+
+	output, _ := renderStats(t, cast, opts)
+
+	return output
+}
+
+func renderStats(t *testing.T, cast *asciicast.Cast, opts svg.Options) (string, svg.Stats) {
+	t.Helper()
+
+	var output bytes.Buffer
+
+	stats, err := svg.Export(*cast, &output, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return output.String(), stats
+}
+
+// svgTest is a case of the tables checking what the svg of cast has, or hasn't.
+type svgTest struct {
+	cast         *asciicast.Cast
+	opts         svg.Options
+	want, absent []string
+}
+
+func runSvgTests(t *testing.T, tests map[string]svgTest) {
+	t.Helper()
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assertSvg(t, render(t, tc.cast, tc.opts), tc.want, tc.absent)
+		})
+	}
+}
+
+// timingTest is a case of the tables checking when the frames of cast show.
+type timingTest struct {
+	cast   *asciicast.Cast
+	opts   svg.Options
+	frames int // Not checked when 0
+	want   []string
+}
+
+func runTimingTests(t *testing.T, tests map[string]timingTest) {
+	t.Helper()
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			events := append([]asciicast.Event(nil), tc.cast.Events...)
+			duration := tc.cast.Header.Duration
+
+			output, stats := renderStats(t, tc.cast, tc.opts)
+			assertSvg(t, output, tc.want, nil)
+
+			if tc.frames > 0 {
+				testutils.Diff(t, stats.Frames, tc.frames)
+			}
+
+			if !strings.HasSuffix(strings.TrimSpace(output), "</svg>") {
+				t.Fatalf("incomplete svg:\n%s", output)
+			}
+
+			// The caller's recording keeps its times
+			testutils.Diff(t, tc.cast.Events, events)
+			testutils.Diff(t, tc.cast.Header.Duration, duration)
+		})
+	}
+}
+
+// assertSvg fails if any of want isn't in output, or any of absent is.
+func assertSvg(t *testing.T, output string, want, absent []string) {
+	t.Helper()
+
+	for _, s := range want {
+		if !strings.Contains(output, s) {
+			t.Fatalf("%s not found in svg:\n%s", s, output)
+		}
+	}
 
-		// Pass empty override bg and text colors
-		svg.Export(*cast, &output, "", "", false)
+	for _, s := range absent {
+		if strings.Contains(output, s) {
+			t.Fatalf("%s found in svg:\n%s", s, output)
+		}
 	}
 }
@@ -0,0 +1,62 @@
+package svg
+
+// Theme holds the colors used to paint the window and the default text.
+// Foreground only paints text in the terminal default color, text with an
+// explicit color keeps it, even palette 7. When empty the default text is
+// drawn in palette 7 light grey, as the terminal would.
+type Theme struct {
+	Background string
+	Foreground string
+	Buttons    [3]string
+	// Color of the window title bar, Background when empty
+	Window string
+	// Palette colors that don't suit the background, replaced by others as lowercase #rrggbb.
+	// Options.ColorMap goes first
+	Colors map[string]string
+}
+
+var (
+	// DarkTheme is the look used when nothing else is specified.
+	DarkTheme = Theme{
+		Background: "#282d35",
+		Buttons:    [3]string{"#ff5f58", "#ffbd2e", "#18c132"},
+	}
+
+	// LightTheme draws dark text over a light window. The palette whites and
+	// yellows would barely show on it, they are darkened.
+	LightTheme = Theme{
+		Background: "#fafafa",
+		Foreground: "#383a42",
+		Buttons:    [3]string{"#fe5f57", "#febc2e", "#28c840"},
+		Colors: map[string]string{
+			"#e5e5e5": "#696c77", // White
+			"#ffffff": "#383a42", // Bright white
+			"#cdcd00": "#986801", // Yellow
+			"#ffff00": "#c18401", // Bright yellow
+		},
+	}
+)
+
+// orDefault fills the missing window colors with the ones from DarkTheme.
+func (t Theme) orDefault() Theme {
+	if t.Background == "" {
+		t.Background = DarkTheme.Background
+	}
+
+	for i := range t.Buttons {
+		if t.Buttons[i] == "" {
+			t.Buttons[i] = DarkTheme.Buttons[i]
+		}
+	}
+
+	return t
+}
+
+// titleBar returns the color of the window title bar.
+func (t Theme) titleBar() string {
+	if t.Window != "" {
+		return t.Window
+	}
+
+	return t.Background
+}
@@ -0,0 +1,40 @@
+package svg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mrmarble/termsvg/pkg/asciicast"
+)
+
+// brokenTerminal fails like vt10x does on the sequences it can't handle.
+type brokenTerminal struct{ err error }
+
+func (b brokenTerminal) Write([]byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	panic("runtime error: index out of range [-1]")
+}
+
+func TestTerminalFailure(t *testing.T) {
+	tests := map[string]brokenTerminal{
+		"Panic": {},
+		"Error": {errors.New("broken")},
+	}
+
+	for name, term := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := write(term, asciicast.Event{Time: 1.5, EventType: asciicast.Output, EventData: "x"})
+			if !errors.Is(err, ErrTerminal) {
+				t.Fatalf("expected ErrTerminal, got %v", err)
+			}
+
+			if !strings.Contains(err.Error(), "at 1.50s") {
+				t.Fatalf("time of the event not found in %q", err)
+			}
+		})
+	}
+}
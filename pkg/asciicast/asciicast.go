@@ -9,10 +9,17 @@ import (
 	"encoding/json"
 	"math"
 	"os"
+	"sort"
 	"strings"
 	"time"
 )
 
+// Terminal size given to recordings without one, the usual default of terminal emulators.
+const (
+	DefaultWidth  = 80
+	DefaultHeight = 24
+)
+
 // header is JSON-encoded object containing recording meta-data.
 // fields with 'omitempty' are optional by asciicast v2 format
 type header struct {
@@ -34,6 +41,9 @@ type header struct {
 type Cast struct {
 	Header header
 	Events []Event
+
+	// DefaultSize is set by Unmarshal when the header had no usable size and the defaults were used.
+	DefaultSize bool
 }
 
 // New will instantiate new Cast with basic medatada (version, timestamp and environment).
@@ -91,7 +101,18 @@ func Unmarshal(data []byte) (*Cast, error) {
 
 	// Duration field isn't required as v2 documentation but is needed for exporting purposes.
 	if cast.Header.Duration == 0 {
-		cast.Header.Duration = cast.Events[len(cast.Events)-1].Time
+		cast.RecomputeDuration()
+	}
+
+	// Files without a header, or with a broken one, would replay on a terminal without cells
+	if cast.Header.Width <= 0 {
+		cast.Header.Width = DefaultWidth
+		cast.DefaultSize = true
+	}
+
+	if cast.Header.Height <= 0 {
+		cast.Header.Height = DefaultHeight
+		cast.DefaultSize = true
 	}
 
 	return &cast, nil
@@ -117,6 +138,15 @@ func (c *Cast) CapRelativeTime(limit float64) {
 	}
 }
 
+// FloorRelativeTime makes the time between each event at least limit
+func (c *Cast) FloorRelativeTime(limit float64) {
+	if limit > 0 {
+		for i, frame := range c.Events {
+			c.Events[i].Time = math.Max(frame.Time, limit)
+		}
+	}
+}
+
 // ToAbsoluteTime converts event time to the absolute difference from the start.
 // This is the default time format.
 func (c *Cast) ToAbsoluteTime() {
@@ -126,14 +156,87 @@ func (c *Cast) ToAbsoluteTime() {
 		time += frame.Time
 		c.Events[i].Time = time
 	}
+
+	c.extendDuration()
 }
 
-// AdjustSpeed changes the time of each event.
-// Slower < 1.0 > Faster.
+// AdjustSpeed changes the time of each event, and the duration along with them.
+// Slower < 1.0 > Faster. A speed of 0 or less leaves the times as they are.
 func (c *Cast) AdjustSpeed(speed float64) {
+	if speed <= 0 {
+		return
+	}
+
 	for i := range c.Events {
 		c.Events[i].Time /= speed
 	}
+
+	c.Header.Duration /= speed
+}
+
+// RecomputeDuration sets the header duration to the time of the last event.
+// Events must be in absolute time. Operations editing the events keep the duration
+// reaching the last event for you, but leave a longer one given by the header.
+func (c *Cast) RecomputeDuration() {
+	c.Header.Duration = 0
+	if len(c.Events) > 0 {
+		c.Header.Duration = c.Events[len(c.Events)-1].Time
+	}
+}
+
+// extendDuration makes the header duration last until the last event at least.
+// Recordings can go on after it, so a longer duration is kept.
+func (c *Cast) extendDuration() {
+	if len(c.Events) > 0 && c.Events[len(c.Events)-1].Time > c.Header.Duration {
+		c.Header.Duration = c.Events[len(c.Events)-1].Time
+	}
+}
+
+// ClampTimes makes event times never go backward, moving events earlier than the previous one
+// (or than the start) to its time. Returns how many events were moved.
+func (c *Cast) ClampTimes() int {
+	clamped := 0
+	prev := 0.
+
+	for i, event := range c.Events {
+		if event.Time < prev {
+			c.Events[i].Time = prev
+			clamped++
+		}
+
+		prev = c.Events[i].Time
+	}
+
+	if clamped > 0 {
+		c.extendDuration()
+	}
+
+	return clamped
+}
+
+// HasOutput reports whether any event is output, the only kind changing the screen.
+func (c *Cast) HasOutput() bool {
+	for _, event := range c.Events {
+		if event.EventType == Output {
+			return true
+		}
+	}
+
+	return false
+}
+
+// KeepOutput drops the events that aren't output, like input, which don't change the screen.
+// The duration is kept, the recording still lasts as long.
+func (c *Cast) KeepOutput() {
+	events := c.Events[:0]
+
+	for _, event := range c.Events {
+		if event.EventType == Output {
+			events = append(events, event)
+		}
+	}
+
+	c.Events = events
 }
 
 // Compress chains together events with the same time.
@@ -157,20 +260,63 @@ func (c *Cast) Compress() {
 	c.Events = events
 }
 
+// LimitEvents merges events together so at most limit remain, kept evenly spread across the recording.
+// Dropped events are prepended to the next kept one so the final screen of each kept event doesn't change.
+func (c *Cast) LimitEvents(limit int) {
+	if limit <= 0 || len(c.Events) <= limit {
+		return
+	}
+
+	events := make([]Event, 0, limit)
+	data := ""
+
+	for i, event := range c.Events {
+		data += event.EventData
+
+		if i == (len(events)+1)*len(c.Events)/limit-1 {
+			event.EventData = data
+			events = append(events, event)
+			data = ""
+		}
+	}
+
+	c.Events = events
+	c.extendDuration()
+}
+
+// EventAt returns the last event at or before t, which is what the screen shows at that time.
+// Events must be in absolute time. Returns nil if t is before the first event.
+func (c *Cast) EventAt(t float64) *Event {
+	i := sort.Search(len(c.Events), func(i int) bool {
+		return c.Events[i].Time > t
+	})
+	if i == 0 {
+		return nil
+	}
+
+	return &c.Events[i-1]
+}
+
 // Asciicast format is not valid JSON so json.Unmarshal returns an error.
 // This function parses the file line by line to circumvent that.
 func (c *Cast) fromJSON(data string) error {
+	if strings.TrimSpace(data) == "" {
+		return ErrEmptyFile
+	}
+
 	lines := strings.Split(data, "\n")
-	if lines[0][0] == '{' {
+	first := 0
+
+	if strings.HasPrefix(lines[0], "{") {
 		err := json.Unmarshal([]byte(lines[0]), &c.Header)
 		if err != nil {
-			return err
+			return &HeaderError{Err: err}
 		}
 
-		lines = lines[1:]
+		first = 1
 	}
 
-	for _, line := range lines {
+	for i, line := range lines[first:] {
 		if line == "" {
 			continue
 		}
@@ -178,7 +324,7 @@ func (c *Cast) fromJSON(data string) error {
 
 		err := json.Unmarshal([]byte(line), &event)
 		if err != nil {
-			return err
+			return &EventError{Line: first + i + 1, Err: err}
 		}
 
 		c.Events = append(c.Events, event)
@@ -1,6 +1,7 @@
 package asciicast_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/mrmarble/termsvg/internal/testutils"
@@ -104,6 +105,198 @@ func TestAdjustSpeed(t *testing.T) {
 	testutils.Diff(t, cast.Events[2].Time, float64(1.5))
 }
 
+func TestAdjustSpeedSlower(t *testing.T) {
+	tests := map[string]struct {
+		speed    float64
+		duration float64
+	}{
+		"Quarter":  {0.25, 12},
+		"Zero":     {0, 3},
+		"Negative": {-1, 3},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cast := setup(t)
+			cast.RecomputeDuration()
+
+			cast.AdjustSpeed(tc.speed)
+
+			testutils.Diff(t, cast.Header.Duration, tc.duration)
+		})
+	}
+}
+
+func TestLimitEvents(t *testing.T) {
+	cast := asciicast.New()
+	for i := 1; i <= 1000; i++ {
+		cast.Events = append(cast.Events, asciicast.Event{Time: float64(i), EventType: asciicast.Output, EventData: "x"})
+	}
+
+	cast.LimitEvents(100)
+
+	testutils.Diff(t, len(cast.Events), 100)
+	testutils.Diff(t, cast.Events[0].Time, float64(10))
+	testutils.Diff(t, cast.Events[50].Time, float64(510))
+	testutils.Diff(t, cast.Events[99].Time, float64(1000))
+
+	data := ""
+	for _, event := range cast.Events {
+		data += event.EventData
+	}
+
+	testutils.Diff(t, len(data), 1000)
+}
+
+func TestRecomputeDuration(t *testing.T) {
+	tests := map[string]struct {
+		edit   func(*asciicast.Cast)
+		output float64
+	}{
+		"Speed":      {func(c *asciicast.Cast) { c.AdjustSpeed(2) }, 1.5},
+		"Idle limit": {func(c *asciicast.Cast) { c.ToRelativeTime(); c.CapRelativeTime(0.5); c.ToAbsoluteTime() }, 3},
+		"Floor":      {func(c *asciicast.Cast) { c.ToRelativeTime(); c.FloorRelativeTime(2); c.ToAbsoluteTime() }, 6},
+		"Limit":      {func(c *asciicast.Cast) { c.LimitEvents(1) }, 3},
+		"No events":  {func(c *asciicast.Cast) { c.Events = nil; c.RecomputeDuration() }, 0},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cast := setup(t)
+			cast.Header.Duration = 3
+
+			tc.edit(cast)
+
+			testutils.Diff(t, cast.Header.Duration, tc.output)
+		})
+	}
+}
+
+func TestClampTimes(t *testing.T) {
+	cast := asciicast.New()
+	for _, time := range []float64{-1, 1, 3, 2, 4} {
+		cast.Events = append(cast.Events, asciicast.Event{Time: time, EventType: asciicast.Output, EventData: "x"})
+	}
+
+	testutils.Diff(t, cast.ClampTimes(), 2)
+
+	times := []float64{}
+	for _, event := range cast.Events {
+		times = append(times, event.Time)
+	}
+
+	testutils.Diff(t, times, []float64{0, 1, 3, 3, 4})
+	testutils.Diff(t, cast.Header.Duration, float64(4))
+}
+
+func TestKeepOutput(t *testing.T) {
+	cast := asciicast.New()
+	cast.Events = append(cast.Events,
+		asciicast.Event{Time: 1, EventType: asciicast.Input, EventData: "l"},
+		asciicast.Event{Time: 2, EventType: asciicast.Output, EventData: "ls"},
+		asciicast.Event{Time: 3, EventType: asciicast.Input, EventData: "\r"},
+	)
+	cast.RecomputeDuration()
+
+	testutils.Diff(t, cast.HasOutput(), true)
+
+	cast.KeepOutput()
+
+	testutils.Diff(t, cast.Events, []asciicast.Event{{Time: 2, EventType: asciicast.Output, EventData: "ls"}})
+	testutils.Diff(t, cast.Header.Duration, float64(3))
+
+	cast.Events = cast.Events[:0]
+	testutils.Diff(t, cast.HasOutput(), false)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	header := `{"version":2,"width":80,"height":24}` + "\n"
+
+	tests := map[string]struct {
+		input  string
+		empty  bool
+		header bool
+		line   int // Line of the event error
+	}{
+		"Empty file":     {input: "  \n", empty: true},
+		"Bad header":     {input: "{\"version\":\n", header: true},
+		"Missing field":  {input: header + `[1.0, "o"]`, line: 2},
+		"Not an event":   {input: header + `[1.0, "o", "ok"]` + "\nnope\n", line: 3},
+		"Wrong type":     {input: header + `[1.0, 2, "x"]`, line: 2},
+		"Without header": {input: `[1.0, "o", "ok"]` + "\n" + `["1", "o", "x"]`, line: 2},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := asciicast.Unmarshal([]byte(tc.input))
+
+			var headerErr *asciicast.HeaderError
+
+			var eventErr *asciicast.EventError
+
+			testutils.Diff(t, errors.Is(err, asciicast.ErrEmptyFile), tc.empty)
+			testutils.Diff(t, errors.As(err, &headerErr), tc.header)
+			testutils.Diff(t, errors.As(err, &eventErr), tc.line > 0)
+
+			if eventErr != nil {
+				testutils.Diff(t, eventErr.Line, tc.line)
+			}
+		})
+	}
+}
+
+func TestUnmarshalSize(t *testing.T) {
+	tests := map[string]struct {
+		input         string
+		width, height int
+		defaulted     bool
+	}{
+		"Zero":           {`{"version":2,"width":0,"height":0}`, 80, 24, true},
+		"Negative":       {`{"version":2,"width":-1,"height":10}`, 80, 10, true},
+		"Without header": {`[1.0, "o", "ok"]`, 80, 24, true},
+		"Given":          {`{"version":2,"width":100,"height":30}`, 100, 30, false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cast, err := asciicast.Unmarshal([]byte(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			testutils.Diff(t, cast.Header.Width, tc.width)
+			testutils.Diff(t, cast.Header.Height, tc.height)
+			testutils.Diff(t, cast.DefaultSize, tc.defaulted)
+		})
+	}
+}
+
+func TestEventAt(t *testing.T) {
+	cast := setup(t)
+
+	tests := map[string]struct {
+		input  float64
+		output string
+	}{
+		"Before first":   {0.5, ""},
+		"On first":       {1, "First"},
+		"Between frames": {2.5, "Second"},
+		"On last":        {3, "Third"},
+		"After last":     {10, "Third"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := ""
+			if event := cast.EventAt(tc.input); event != nil {
+				got = event.EventData
+			}
+
+			testutils.Diff(t, got, tc.output)
+		})
+	}
+}
+
 func setup(t *testing.T) *asciicast.Cast {
 	t.Helper()
 
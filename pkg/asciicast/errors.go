@@ -0,0 +1,39 @@
+package asciicast
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrEmptyFile is returned when unmarshaling data with neither a header nor events.
+var ErrEmptyFile = errors.New("empty asciicast file")
+
+// ErrNoOutput is returned when rendering a recording without output events, input alone doesn't change the screen.
+var ErrNoOutput = errors.New("recording contains no output to render")
+
+// HeaderError is returned when the header line can't be parsed.
+type HeaderError struct {
+	Err error
+}
+
+func (e *HeaderError) Error() string {
+	return fmt.Sprintf("invalid header: %v", e.Err)
+}
+
+func (e *HeaderError) Unwrap() error {
+	return e.Err
+}
+
+// EventError is returned when an event line can't be parsed.
+type EventError struct {
+	Line int // Counting from 1, header included
+	Err  error
+}
+
+func (e *EventError) Error() string {
+	return fmt.Sprintf("invalid event on line %d: %v", e.Line, e.Err)
+}
+
+func (e *EventError) Unwrap() error {
+	return e.Err
+}
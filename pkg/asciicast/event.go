@@ -2,8 +2,12 @@ package asciicast
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 )
 
+var errEventFormat = errors.New("event must be [time, type, data]")
+
 type eventType string
 
 // Event is a 3-tuple encoded as JSON array.
@@ -25,9 +29,28 @@ func (e *Event) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	e.Time = v[0].(float64)
-	e.EventType = eventType(v[1].(string))
-	e.EventData = v[2].(string)
+	if len(v) != 3 { //nolint:gomnd
+		return fmt.Errorf("%w: expected 3 fields, got %d", errEventFormat, len(v))
+	}
+
+	time, ok := v[0].(float64)
+	if !ok {
+		return fmt.Errorf("%w: time %v is not a number", errEventFormat, v[0])
+	}
+
+	typ, ok := v[1].(string)
+	if !ok {
+		return fmt.Errorf("%w: type %v is not a string", errEventFormat, v[1])
+	}
+
+	text, ok := v[2].(string)
+	if !ok {
+		return fmt.Errorf("%w: data %v is not a string", errEventFormat, v[2])
+	}
+
+	e.Time = time
+	e.EventType = eventType(typ)
+	e.EventData = text
 
 	return nil
 }
@@ -3,6 +3,7 @@ package color
 import (
 	"fmt"
 	"image/color"
+	"math"
 
 	"github.com/hinshun/vt10x"
 )
@@ -15,7 +16,7 @@ func GetColor(c vt10x.Color) string {
 		return colors[int(vt10x.LightGrey)]
 	case c >= 1<<8:
 		rgb := intToRGB(int(c))
-		return fmt.Sprintf("#%02x%02x%02x", rgb.R, rgb.B, rgb.G)
+		return fmt.Sprintf("#%02x%02x%02x", rgb.R, rgb.G, rgb.B)
 	default:
 		return colors[int(c)]
 	}
@@ -29,3 +30,77 @@ func intToRGB(c int) color.RGBA {
 		A: 255,
 	}
 }
+
+// Contrast returns the WCAG contrast ratio between two colors, from 1 to 21.
+func Contrast(a, b color.RGBA) float64 {
+	la, lb := luminance(a), luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+
+	return (la + 0.05) / (lb + 0.05) //nolint:gomnd
+}
+
+// EnsureContrast mixes the "#rrggbb" fg with white, or black on light backgrounds,
+// just enough to reach ratio against bg. Colors that can't be parsed are returned as is.
+func EnsureContrast(fg, bg string, ratio float64) string {
+	from, err := parseHex(fg)
+	if err != nil {
+		return fg
+	}
+
+	back, err := parseHex(bg)
+	if err != nil || Contrast(from, back) >= ratio {
+		return fg
+	}
+
+	to := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	if luminance(back) > 0.179 { //nolint:gomnd // black and white contrast the same here
+		to = color.RGBA{A: 255}
+	}
+
+	// Search the smallest mix, the full one is the best we can do
+	lo, hi := 0.0, 1.0
+	for i := 0; i < 16; i++ {
+		mid := (lo + hi) / 2 //nolint:gomnd
+		if Contrast(mix(from, to, mid), back) >= ratio {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+
+	c := mix(from, to, hi)
+
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func parseHex(hex string) (color.RGBA, error) {
+	c := color.RGBA{A: 255}
+
+	_, err := fmt.Sscanf(hex, "#%02x%02x%02x", &c.R, &c.G, &c.B)
+
+	return c, err
+}
+
+// luminance is the relative luminance of c as defined by WCAG.
+func luminance(c color.RGBA) float64 {
+	channel := func(v uint8) float64 {
+		f := float64(v) / 255 //nolint:gomnd
+		if f <= 0.03928 {     //nolint:gomnd
+			return f / 12.92 //nolint:gomnd
+		}
+
+		return math.Pow((f+0.055)/1.055, 2.4) //nolint:gomnd
+	}
+
+	return 0.2126*channel(c.R) + 0.7152*channel(c.G) + 0.0722*channel(c.B) //nolint:gomnd
+}
+
+func mix(a, b color.RGBA, t float64) color.RGBA {
+	channel := func(x, y uint8) uint8 {
+		return uint8(math.Round(float64(x) + (float64(y)-float64(x))*t))
+	}
+
+	return color.RGBA{R: channel(a.R, b.R), G: channel(a.G, b.G), B: channel(a.B, b.B), A: 255}
+}
@@ -0,0 +1,83 @@
+package color_test
+
+import (
+	"testing"
+
+	"github.com/hinshun/vt10x"
+	"github.com/mrmarble/termsvg/internal/testutils"
+	"github.com/mrmarble/termsvg/pkg/color"
+)
+
+func TestBrightColors(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		cell   func(vt10x.Glyph) vt10x.Color
+		output string
+	}{
+		"Bright red foreground":   {"\u001b[91mx", fg, "#ff0000"},
+		"Bright white foreground": {"\u001b[97mx", fg, "#ffffff"},
+		"Bright green background": {"\u001b[102mx", bg, "#00ff00"},
+		"Bright cyan background":  {"\u001b[106mx", bg, "#00ffff"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			term := vt10x.New(vt10x.WithSize(2, 1))
+
+			_, err := term.Write([]byte(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			testutils.Diff(t, color.GetColor(tc.cell(term.Cell(0, 0))), tc.output)
+		})
+	}
+}
+
+func TestExtendedColors(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		cell   func(vt10x.Glyph) vt10x.Color
+		output string
+	}{
+		"256 color cube foreground": {"\u001b[38;5;208mx", fg, "#ff8700"},
+		"256 grayscale background":  {"\u001b[48;5;244mx", bg, "#808080"},
+		"Truecolor foreground":      {"\u001b[38;2;10;20;30mx", fg, "#0a141e"},
+		"Truecolor background":      {"\u001b[48;2;200;100;50mx", bg, "#c86432"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			term := vt10x.New(vt10x.WithSize(2, 1))
+
+			_, err := term.Write([]byte(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			testutils.Diff(t, color.GetColor(tc.cell(term.Cell(0, 0))), tc.output)
+		})
+	}
+}
+
+func TestEnsureContrast(t *testing.T) {
+	tests := map[string]struct {
+		fg, bg string
+		ratio  float64
+		output string
+	}{
+		"High contrast is unchanged": {"#e5e5e5", "#000000", 4.5, "#e5e5e5"},
+		"Dark gray on black":         {"#333333", "#000000", 4.5, "#757575"},
+		"Light gray on white":        {"#dddddd", "#ffffff", 4.5, "#767676"},
+		"Unknown background":         {"#333333", "black", 4.5, "#333333"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			testutils.Diff(t, color.EnsureContrast(tc.fg, tc.bg, tc.ratio), tc.output)
+		})
+	}
+}
+
+func fg(g vt10x.Glyph) vt10x.Color { return g.FG }
+func bg(g vt10x.Glyph) vt10x.Color { return g.BG }